@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/scottmcleodjr/gebvm/aot"
+)
+
+const (
+	aotHelpText string = `Provide the bytecode file to translate as an argument.
+
+  Example: ./gebvm aot -o hello_world/main.go hello_world.geb
+
+`
+)
+
+// aotCommand translates a bytecode file into Go source.
+func aotCommand(args []string) int {
+	flags := flag.NewFlagSet("aot", flag.ExitOnError)
+	output := flags.String("o", "", "Go output file (default: stdout)")
+	pkg := flags.String("package", "main", "package `name` of the output.  A main package also gets a main function")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, aotHelpText)
+		return 1
+	}
+
+	e, err := loadExecutable(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	source, err := aot.Translate(e, aot.Options{Package: *pkg, Source: filepath.Base(flags.Arg(0))})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	if *output == "" {
+		os.Stdout.Write(source)
+		return 0
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing output file: %s\n", err)
+		return 1
+	}
+	return 0
+}
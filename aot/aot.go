@@ -0,0 +1,496 @@
+// Package aot translates GebVM programs into Go source that runs them
+// natively.
+//
+// Every instruction reachable from the entry address is translated, and
+// each basic block becomes a case of a switch on the instruction pointer.
+// The generated code runs on a Machine and falls back to the interpreter
+// for control flow to addresses that were not translated, such as returns
+// to modified return addresses, after any write to translated code, and
+// before any instruction that would fault, so errors are reported exactly
+// as the interpreter reports them.
+package aot
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+
+	"github.com/scottmcleodjr/gebvm/disassembler"
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+// Options configures the generated source.
+type Options struct {
+	Package string // Package name, main if empty.  A main package gets a main function.
+	Source  string // Name of the translated file, for the generated header
+}
+
+// conditions are the Go conditions for the branches that test flags.
+var conditions = map[uint8]string{
+	processor.JumpLessThan:     "m.Negative() != m.Overflow()",
+	processor.JumpGreaterThan:  "!m.Zero() && m.Negative() == m.Overflow()",
+	processor.JumpLessEqual:    "m.Zero() || m.Negative() != m.Overflow()",
+	processor.JumpGreaterEqual: "m.Negative() == m.Overflow()",
+	processor.JumpLower:        "m.Carry()",
+	processor.JumpHigher:       "!m.Carry() && !m.Zero()",
+	processor.JumpLowerSame:    "m.Carry() || m.Zero()",
+	processor.JumpHigherSame:   "!m.Carry()",
+	processor.JumpCarrySet:     "m.Carry()",
+	processor.JumpCarryClear:   "!m.Carry()",
+	processor.JumpZeroSet:      "m.Zero()",
+	processor.JumpZeroClear:    "!m.Zero()",
+}
+
+// logicOperators are the Go operators for the logic instructions.
+var logicOperators = map[uint8]string{
+	processor.LogicalAnd:      "&",
+	processor.LogicalOr:       "|",
+	processor.LogicalXor:      "^",
+	processor.LogicalBitClear: "&^",
+}
+
+// logicLitOperators are the Go operators for the logic instructions with a
+// literal operand.
+var logicLitOperators = map[uint8]string{
+	processor.LogicalAndLit: "&",
+	processor.LogicalOrLit:  "|",
+	processor.LogicalXorLit: "^",
+}
+
+type translator struct {
+	bytes.Buffer
+}
+
+func (t *translator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(t, format, args...)
+}
+
+// Translate returns Go source that runs e.  The source defines
+// Run(m *Machine) int, which loads the program into m, runs it and
+// returns the exit status.
+func Translate(e *executable.Executable, options Options) ([]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if options.Package == "" {
+		options.Package = "main"
+	}
+
+	var image [memory.MemorySize]uint8
+	for _, s := range e.Segments {
+		copy(image[s.Address:], s.Data)
+	}
+	var lines []disassembler.Line
+	leaders := map[int]bool{int(e.Entry): true}
+	for _, line := range disassembler.Recursive(image[:], 0x0000, e.Entry) {
+		if line.Instruction == nil {
+			continue
+		}
+		lines = append(lines, line)
+		if target := line.Instruction.Target(); target >= 0 {
+			leaders[int(line.Operands[target])] = true
+		}
+		if flow := line.Instruction.Flow; flow == processor.FlowBranch || flow == processor.FlowCall {
+			leaders[int(line.Address)+len(line.Bytes)] = true
+		}
+	}
+
+	t := &translator{}
+	source := ""
+	if options.Source != "" {
+		source = " from " + options.Source
+	}
+	t.printf("// Code generated by gebvm aot%s. DO NOT EDIT.\n\n", source)
+	t.printf("package %s\n\n", options.Package)
+	if options.Package == "main" {
+		t.printf("import (\n\"bufio\"\n\"os\"\n\n\"github.com/scottmcleodjr/gebvm/aot\"\n)\n\n")
+		t.printf("func main() {\n")
+		t.printf("m := aot.NewMachine(bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout), bufio.NewWriter(os.Stderr))\n")
+		t.printf("os.Exit(Run(m))\n}\n\n")
+	} else {
+		t.printf("import \"github.com/scottmcleodjr/gebvm/aot\"\n\n")
+	}
+
+	t.printf("// Run runs the program on m and returns its exit status.\n")
+	t.printf("func Run(m *aot.Machine) int {\n")
+	t.segments(e.Segments)
+	t.codeRanges(lines)
+	t.printf("ip := uint16(0x%04X)\n", e.Entry)
+	t.printf("for {\nswitch ip {\n")
+	end, fallsThrough := -1, false
+	for _, line := range lines {
+		address := int(line.Address)
+		if leaders[address] || address != end || !fallsThrough {
+			if fallsThrough {
+				t.next(end, address)
+			}
+			t.printf("case 0x%04X:\n", address)
+		}
+		fallsThrough = t.instruction(line)
+		end = address + len(line.Bytes)
+	}
+	if fallsThrough {
+		t.next(end, -1)
+	}
+	t.printf("default:\nreturn m.Interpret(ip)\n}\n}\n}\n")
+
+	out, err := format.Source(t.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated source: %s", err)
+	}
+	return out, nil
+}
+
+// segments writes the statements loading the program into memory.
+func (t *translator) segments(segments []executable.Segment) {
+	for _, s := range segments {
+		t.printf("m.Load(0x%04X, []uint8{", s.Address)
+		for i, value := range s.Data {
+			if i%16 == 0 {
+				t.printf("\n")
+			}
+			t.printf("0x%02X, ", value)
+		}
+		t.printf("\n})\n")
+	}
+}
+
+// codeRanges writes the statements marking the translated code.
+func (t *translator) codeRanges(lines []disassembler.Line) {
+	start, end := -1, -1
+	for _, line := range lines {
+		address := int(line.Address)
+		if address != end {
+			if start >= 0 {
+				t.printf("m.MarkCode(0x%04X, %d)\n", start, end-start)
+			}
+			start = address
+		}
+		end = address + len(line.Bytes)
+	}
+	if start >= 0 {
+		t.printf("m.MarkCode(0x%04X, %d)\n", start, end-start)
+	}
+}
+
+// next ends a block that continues at address end.  next is the address
+// of the following case, or -1 if there is none.
+func (t *translator) next(end, next int) {
+	if end == next {
+		t.printf("fallthrough\n")
+		return
+	}
+	t.printf("ip = 0x%04X\ncontinue\n", end)
+}
+
+// interpret ends a block by interpreting from address.
+func (t *translator) interpret(address uint16) {
+	t.printf("return m.Interpret(0x%04X)\n", address)
+}
+
+// jump ends a block by continuing at address.
+func (t *translator) jump(address uint16) {
+	t.printf("ip = 0x%04X\ncontinue\n", address)
+}
+
+// checkModified interprets from next if the instruction wrote to
+// translated code.
+func (t *translator) checkModified(next uint16) {
+	t.printf("if m.Modified {\n")
+	t.interpret(next)
+	t.printf("}\n")
+}
+
+// destination writes the code for an ALU instruction that names its
+// destination register.  It runs the R0 form of the instruction, then
+// moves the result to the destination and restores R0.  If the R0 form
+// always falls back to the interpreter, so does the whole instruction.
+func (t *translator) destination(line disassembler.Line, base processor.InstructionInfo) bool {
+	last := len(line.Operands) - 1
+	dst := line.Operands[last]
+	word := base.Opcode == processor.AddWord || base.Opcode == processor.SubtractWord
+	if word && dst == uint16(processor.RegisterCount)-1 {
+		t.interpret(line.Address)
+		return false
+	}
+	baseLine := line
+	baseLine.Instruction = &base
+	baseLine.Operands = line.Operands[:last]
+
+	start := t.Len()
+	t.printf("{\n")
+	if word {
+		t.printf("saved := m.Pointer(0)\n")
+	} else {
+		t.printf("saved := m.R[0]\n")
+	}
+	if !t.instruction(baseLine) {
+		// Nothing after the fallback is reachable, and saved is unused
+		t.Truncate(start)
+		t.interpret(line.Address)
+		return false
+	}
+	if word {
+		t.printf("result := m.Pointer(0)\nm.SetPair(0, saved)\nm.SetPair(%d, result)\n", dst)
+	} else {
+		t.printf("result := m.R[0]\nm.R[0] = saved\nm.R[%d] = result\n", dst)
+	}
+	t.printf("}\n")
+	return true
+}
+
+// offset returns the code for the offset or index operand of a load or
+// store instruction.
+func (t *translator) offset(opcode uint8, operand uint16) string {
+	if opcode == processor.LoadIndexed || opcode == processor.StoreIndexed {
+		return fmt.Sprintf("uint16(m.R[%d])", operand)
+	}
+	return fmt.Sprintf("0x%02X", operand)
+}
+
+// instruction writes the code for line, and returns true if execution
+// can continue at the next instruction.
+func (t *translator) instruction(line disassembler.Line) bool {
+	address := line.Address
+	ops := line.Operands
+	register := func(i int) string {
+		return fmt.Sprintf("m.R[%d]", ops[i])
+	}
+	// Pairs in R7 use an invalid register for the low byte
+	invalidPair := func(indexes ...int) bool {
+		for _, i := range indexes {
+			if ops[i] == uint16(processor.RegisterCount)-1 {
+				return true
+			}
+		}
+		return false
+	}
+	t.printf("// %04X: %s\n", address, line.Text())
+
+	// Fetching a byte from 0xFFFF moves the IP out of bounds
+	if int(address)+len(line.Bytes) > 0xFFFF {
+		t.interpret(address)
+		return false
+	}
+	next := address + uint16(len(line.Bytes))
+
+	opcode := line.Instruction.Opcode
+	if base, _ := processor.LookupMnemonic(line.Instruction.Mnemonic); base.Opcode != opcode {
+		return t.destination(line, base)
+	}
+	if condition, found := conditions[opcode]; found {
+		t.printf("if %s {\n", condition)
+		t.jump(ops[0])
+		t.printf("}\n")
+		return true
+	}
+	if operator, found := logicOperators[opcode]; found {
+		t.printf("m.Logic(%s %s %s)\n", register(0), operator, register(1))
+		return true
+	}
+	if operator, found := logicLitOperators[opcode]; found {
+		t.printf("m.Logic(%s %s 0x%02X)\n", register(0), operator, ops[1])
+		return true
+	}
+
+	switch opcode {
+	case processor.Noop:
+	case processor.MoveLitReg:
+		t.printf("%s = 0x%02X\n", register(1), ops[0])
+	case processor.MoveRegReg:
+		// A move to the same register does nothing
+		if ops[0] != ops[1] {
+			t.printf("%s = %s\n", register(1), register(0))
+		}
+	case processor.MoveLitMem, processor.MoveRegMem:
+		// Pointers in R7 use an invalid register for the low byte
+		if ops[1] == uint16(processor.RegisterCount)-1 {
+			t.interpret(address)
+			return false
+		}
+		value := fmt.Sprintf("0x%02X", ops[0])
+		if opcode == processor.MoveRegMem {
+			value = register(0)
+		}
+		t.printf("m.Write(m.Pointer(%d), %s)\n", ops[1], value)
+		t.checkModified(next)
+	case processor.MoveMemReg:
+		if ops[0] == uint16(processor.RegisterCount)-1 {
+			t.interpret(address)
+			return false
+		}
+		t.printf("%s = m.Memory[m.Pointer(%d)]\n", register(1), ops[0])
+	case processor.LoadOffset, processor.LoadIndexed:
+		if invalidPair(0) {
+			t.interpret(address)
+			return false
+		}
+		t.printf("%s = m.Memory[m.Pointer(%d)+%s]\n", register(2), ops[0], t.offset(opcode, ops[1]))
+	case processor.StoreOffset, processor.StoreIndexed:
+		if invalidPair(1) {
+			t.interpret(address)
+			return false
+		}
+		t.printf("m.Write(m.Pointer(%d)+%s, %s)\n", ops[1], t.offset(opcode, ops[2]), register(0))
+		t.checkModified(next)
+	case processor.MoveLitWord:
+		if invalidPair(1) {
+			t.interpret(address)
+			return false
+		}
+		t.printf("m.SetPair(%d, 0x%04X)\n", ops[1], ops[0])
+	case processor.LogicalShiftLeft:
+		t.printf("m.ShiftLeft(%s, %d)\n", register(0), ops[1])
+	case processor.LogicalShiftRight:
+		t.printf("m.ShiftRight(%s, %d)\n", register(0), ops[1])
+	case processor.ArithShiftRight:
+		t.printf("m.ArithShiftRight(%s, %d)\n", register(0), ops[1])
+	case processor.ShiftLeftReg:
+		t.printf("m.ShiftLeft(%s, %s)\n", register(0), register(1))
+	case processor.ShiftRightReg:
+		t.printf("m.ShiftRight(%s, %s)\n", register(0), register(1))
+	case processor.ArithShiftReg:
+		t.printf("m.ArithShiftRight(%s, %s)\n", register(0), register(1))
+	case processor.Inc:
+		t.printf("%s = m.Inc(%s)\n", register(0), register(0))
+	case processor.Dec:
+		t.printf("%s = m.Dec(%s)\n", register(0), register(0))
+	case processor.Add:
+		t.printf("m.Add(%s, %s)\n", register(0), register(1))
+	case processor.Subtract:
+		t.printf("m.R[0] = m.Subtract(%s, %s)\n", register(0), register(1))
+	case processor.Multiply:
+		t.printf("m.Multiply(%s, %s)\n", register(0), register(1))
+	case processor.Divide:
+		t.printf("if %s == 0 {\n", register(1))
+		t.interpret(address)
+		t.printf("}\n")
+		t.printf("m.Divide(%s, %s)\n", register(0), register(1))
+	case processor.Compare:
+		t.printf("m.Subtract(%s, %s)\n", register(0), register(1))
+	case processor.AddLit:
+		t.printf("m.Add(%s, 0x%02X)\n", register(0), ops[1])
+	case processor.SubtractLit:
+		t.printf("m.R[0] = m.Subtract(%s, 0x%02X)\n", register(0), ops[1])
+	case processor.CompareLit:
+		t.printf("m.Subtract(%s, 0x%02X)\n", register(0), ops[1])
+	case processor.Negate:
+		t.printf("%s = m.Subtract(0, %s)\n", register(0), register(0))
+	case processor.SignedMultiply:
+		t.printf("m.SignedMultiply(%s, %s)\n", register(0), register(1))
+	case processor.SignedDivide, processor.SignedRemainder, processor.Modulo:
+		t.printf("if %s == 0 {\n", register(1))
+		t.interpret(address)
+		t.printf("}\n")
+		method := map[uint8]string{
+			processor.SignedDivide:    "SignedDivide",
+			processor.SignedRemainder: "SignedRemainder",
+			processor.Modulo:          "Modulo",
+		}[opcode]
+		t.printf("m.%s(%s, %s)\n", method, register(0), register(1))
+	case processor.IncWord, processor.DecWord:
+		if invalidPair(0) {
+			t.interpret(address)
+			return false
+		}
+		method := "IncWord"
+		if opcode == processor.DecWord {
+			method = "DecWord"
+		}
+		t.printf("m.%s(%d)\n", method, ops[0])
+	case processor.AddWord, processor.SubtractWord, processor.CompareWord:
+		if invalidPair(0, 1) {
+			t.interpret(address)
+			return false
+		}
+		operands := fmt.Sprintf("m.Pointer(%d), m.Pointer(%d)", ops[0], ops[1])
+		switch opcode {
+		case processor.AddWord:
+			t.printf("m.AddWord(%s)\n", operands)
+		case processor.SubtractWord:
+			t.printf("m.SetPair(0, m.SubtractWord(%s))\n", operands)
+		default:
+			t.printf("m.SubtractWord(%s)\n", operands)
+		}
+	case processor.SignExtend:
+		if invalidPair(1) {
+			t.interpret(address)
+			return false
+		}
+		t.printf("m.SignExtend(%s, %d)\n", register(0), ops[1])
+	case processor.Jump:
+		t.jump(ops[0])
+		return false
+	case processor.JumpEqual, processor.JumpNotEqual:
+		operator := "=="
+		if opcode == processor.JumpNotEqual {
+			operator = "!="
+		}
+		t.printf("if m.R[0] %s %s {\n", operator, register(0))
+		t.jump(ops[1])
+		t.printf("}\n")
+	case processor.StackPushLit, processor.StackPushReg:
+		t.printf("if !m.CanPush(1) {\n")
+		t.interpret(address)
+		t.printf("}\n")
+		if opcode == processor.StackPushLit {
+			t.printf("m.Push(0x%02X)\n", ops[0])
+		} else {
+			t.printf("m.Push(%s)\n", register(0))
+		}
+		t.checkModified(next)
+	case processor.StackPop:
+		t.printf("if m.StackSize == 0 || m.SP == 0x%04X {\n", processor.StackStart)
+		t.interpret(address)
+		t.printf("}\n")
+		t.printf("%s = m.Pop()\n", register(0))
+	case processor.Call:
+		t.printf("if !m.CanPush(9) {\n")
+		t.interpret(address)
+		t.printf("}\n")
+		t.printf("m.Call(0x%04X)\n", next)
+		t.checkModified(ops[0])
+		t.jump(ops[0])
+		return false
+	case processor.Return:
+		t.printf("ip = m.Return()\ncontinue\n")
+		return false
+	case processor.ReturnInterrupt:
+		t.printf("ip = m.ReturnInterrupt()\ncontinue\n")
+		return false
+	case processor.LoadFrame:
+		t.printf("if a, ok := m.FrameAddress(%d); ok {\n%s = m.Memory[a]\n} else {\n", int8(ops[0]), register(1))
+		t.interpret(address)
+		t.printf("}\n")
+	case processor.StoreFrame:
+		t.printf("if a, ok := m.FrameAddress(%d); ok {\nm.Write(a, %s)\n} else {\n", int8(ops[1]), register(0))
+		t.interpret(address)
+		t.printf("}\n")
+		t.checkModified(next)
+	case processor.Drop:
+		t.printf("if m.StackSize < 0x%02X {\n", ops[0])
+		t.interpret(address)
+		t.printf("}\n")
+		t.printf("m.Drop(0x%02X)\n", ops[0])
+	case processor.Print:
+		t.printf("m.Print(0x%04X, 0x%02X)\n", ops[0], ops[1])
+	case processor.ReadInput:
+		t.printf("if c, ok := m.ReadInput(); ok {\n%s = c\n} else {\n", register(0))
+		t.interpret(address)
+		t.printf("}\n")
+	case processor.EnableInterrupts:
+		t.printf("m.InterruptsEnabled = true\n")
+	case processor.DisableInterrupts:
+		t.printf("m.InterruptsEnabled = false\n")
+	case processor.Halt:
+		t.printf("return m.Halt(0x%04X)\n", next)
+		return false
+	default:
+		// Not translated
+		t.interpret(address)
+		return false
+	}
+	return true
+}
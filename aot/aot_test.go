@@ -0,0 +1,617 @@
+package aot_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/aot"
+	"github.com/scottmcleodjr/gebvm/assembler"
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+// stateFormat is written after the output of each run, so the final
+// machine states are compared too.
+const stateFormat = "\n-- R %v IP %04X SP %04X size %d flags %s EI %t memory %x\n"
+
+type program struct {
+	name   string
+	source string // Assembly source, or empty to use image
+	image  []uint8
+	input  string
+}
+
+var programs = []program{
+	{name: "arithmetic", source: `
+        MLR 0x7F, R2
+        MLR 0x01, R3
+        ADD R2, R3
+        JLT f0
+        INC R1
+f0:     JCS c0
+        INC R1
+c0:     MRR R1, R1
+        MRR R0, R4
+        SUB R3, R2
+        JLT f1
+        INC R1
+f1:     JCS c1
+        INC R1
+c1:     MRR R1, R1
+        MRR R0, R5
+        MLR 0x10, R6
+        MUL R6, R6
+        JLT f2
+        INC R1
+f2:     JCS c2
+        INC R1
+c2:     MRR R1, R1
+        MLR 0x07, R7
+        DIV R2, R7
+        JLT f3
+        INC R1
+f3:     JCS c3
+        INC R1
+c3:     MRR R1, R1
+        LSL R2, 1
+        JLT f4
+        INC R1
+f4:     JCS c4
+        INC R1
+c4:     MRR R1, R1
+        LSR R3, 1
+        JLT f5
+        INC R1
+f5:     JCS c5
+        INC R1
+c5:     MRR R1, R1
+        LND R2, R3
+        JLT f6
+        INC R1
+f6:     JCS c6
+        INC R1
+c6:     MRR R1, R1
+        LOR R2, R3
+        JLT f7
+        INC R1
+f7:     JCS c7
+        INC R1
+c7:     MRR R1, R1
+        LXR R2, R3
+        JLT f8
+        INC R1
+f8:     JCS c8
+        INC R1
+c8:     MRR R1, R1
+        LBC R2, R3
+        JLT f9
+        INC R1
+f9:     JCS c9
+        INC R1
+c9:     MRR R1, R1
+        INC R2
+        JLT f10
+        INC R1
+f10:    JCS c10
+        INC R1
+c10:    MRR R1, R1
+        DEC R3
+        JLT f11
+        INC R1
+f11:    JCS c11
+        INC R1
+c11:    MRR R1, R1
+        DEC R3
+        JLT f12
+        INC R1
+f12:    JCS c12
+        INC R1
+c12:    MRR R1, R1
+        CMP R2, R3
+        JLT f13
+        INC R1
+f13:    JCS c13
+        INC R1
+c13:    MRR R1, R1
+        MLR 0x80, R2
+        MLR 0x00, R3
+        MRM R4, R2
+        MMR R2, R6
+        MLM 0x2A, R2
+        PNT 0x8000, 2
+        HLT
+`},
+	{name: "branches", source: `
+        MLR 0x00, R2
+        MLR 0x90, R3
+        MLR 0x10, R4
+loop:   CMP R3, R4
+        JLT lt
+        INC R2
+lt:     JGT gt
+        INC R2
+gt:     JLE le
+        INC R2
+le:     JGE ge
+        INC R2
+ge:     JLO lo
+        INC R2
+lo:     JHI hi
+        INC R2
+hi:     JLS ls
+        INC R2
+ls:     JHS hs
+        INC R2
+hs:     JCS cs
+        INC R2
+cs:     JCC cc
+        INC R2
+cc:     JZS zs
+        INC R2
+zs:     JZC zc
+        INC R2
+zc:     ADD R3, R4
+        MRR R0, R3
+        MLR 0x00, R0
+        JNE R3, loop
+        JEQ R3, done
+        NOP
+done:   HLT
+`},
+	{name: "calls", source: `
+        MLR 0x03, R2
+        CLL outer
+        SPL 0x11
+        SPR R2
+        STP R5
+        STP R6
+        HLT
+outer:  SPL 0x22
+        SPL 0x33
+        MLR 0x09, R2
+        CLL inner
+        RET
+inner:  SPL 0x44
+        DEC R2
+        MLR 0x00, R0
+        JEQ R2, back
+        CLL inner
+back:   RET
+`},
+	{name: "self modifying", source: `
+        MLR 0x00, R2
+        MLR 0x01, R3
+again:  INC R4
+        MLM 0x05, R2
+        MLR 0x05, R0
+        JNE R4, again
+        HLT
+`},
+	{name: "print and input", input: "hi", source: `
+        RIN R2
+        RIN R3
+        MLR 0x80, R4
+        MLR 0x00, R5
+        MRM R2, R4
+        INC R5
+        MRM R3, R4
+        PNT 0x8000, 2
+        PNT text, 3
+        EI
+        DI
+        RIN R6
+        HLT
+text:   .string "\n\xE9!"
+`},
+	{name: "divide by zero", source: `
+        MLR 0x08, R2
+        DIV R2, R3
+        HLT
+`},
+	{name: "stack overflow", source: `
+again:  SPL 0x01
+        JMP again
+`},
+	{name: "call overflow", source: `
+again:  CLL again
+`},
+	{name: "stack underflow", source: `
+        STP R2
+`},
+	{name: "modified return address", source: `
+        CLL routine
+        HLT
+        HLT
+routine: STP R2
+        STP R3
+        INC R2
+        SPR R3
+        SPR R2
+        RET
+`},
+	{name: "pointer in R7", source: `
+        MLM 0x01, R7
+        HLT
+`},
+	{name: "word arithmetic", source: `
+        MLW 0x7FFF, R2
+        MLW 0x0001, R4
+        ADW R2, R4
+        JLT w0
+        INC R6
+w0:     JCS w1
+        INC R6
+w1:     SBW R4, R2
+        JLT w2
+        INC R6
+w2:     JCS w3
+        INC R6
+w3:     INW R2
+        JLT w4
+        INC R6
+w4:     DCW R4
+        JLT w5
+        INC R6
+w5:     CPW R2, R4
+        JHI w6
+        INC R6
+w6:     HLT
+`},
+	{name: "signed arithmetic", source: `
+        MLR 0xF9, R2
+        MLR 0x02, R3
+        NEG R2
+        JLT s0
+        INC R6
+s0:     SML R2, R3
+        MRR R0, R4
+        MLR 0x10, R5
+        SML R5, R5
+        JCS s1
+        INC R6
+s1:     NEG R3
+        SDV R2, R3
+        MRR R0, R4
+        SRM R2, R3
+        JZS s2
+        INC R6
+s2:     MOD R2, R3
+        MRR R0, R5
+        MLR 0x80, R2
+        MLR 0xFF, R3
+        SDV R2, R3
+        JLT s3
+        INC R6
+s3:     ASR R2, 3
+        JCS s4
+        INC R6
+s4:     SXT R0, R4
+        NEG R7
+        MRR R7, R1
+        HLT
+`},
+	{name: "immediates", source: `
+        MLR 0x7F, R2
+        ADI R2, 0x01
+        JLT i0
+        INC R6
+i0:     MRR R0, R3
+        SBI R3, 0x81
+        JCS i1
+        INC R6
+i1:     CPI R0, 0xFF
+        JZS i2
+        INC R6
+i2:     ANI R2, 0x0F
+        ORI R0, 0xA0
+        XRI R0, 0x55
+        MRR R0, R4
+        MLR 0x03, R5
+        LLR R4, R5
+        JCS i3
+        INC R6
+i3:     LRR R4, R5
+        ARR R3, R5
+        MRR R0, R7
+        HLT
+`},
+	{name: "destination forms", source: `
+        MLR 0xAA, R0
+        MLR 0xF0, R1
+        MLR 0x10, R2
+        ADD R1, R2, R3
+        JCS d0
+        INC R6
+d0:     SUB R1, R2, R4
+        JLT d1
+        INC R6
+d1:     ANI R4, 0x0F, R5
+        ORI R1, 0x0F, R5
+        LSL R5, 1, R0
+        SML R1, R2, R3
+        DIV R1, R2, R6
+        MLW 0x12FF, R2
+        MLW 0x0101, R4
+        ADW R2, R4, R6
+        SBW R2, R4, R0
+        HLT
+`},
+	{name: "destination divide by zero", source: `
+        MLR 0x42, R0
+        MOD R1, R2, R3
+`},
+	{name: "signed divide by zero", source: `
+        SRM R2, R3
+`},
+	{name: "offset and index addressing", source: `
+        MLW 0x80F0, R2
+        MLR 0x2A, R1
+        STO R1, R2, 0x20
+        LDO R2, 0x20, R0
+        MLR 0x30, R6
+        STX R0, R2, R6
+        LDX R2, R6, R5
+        MLW 0xFFF0, R2
+        STO R1, R2, 0x1F
+        MLR 0x11, R6
+        LDX R2, R6, R4
+        MLW data, R2
+        MLR 0xFF, R1
+        MLR 0x00, R6
+        STX R1, R2, R6  ; Replaces INC with HLT
+data:   INC R5
+        HLT
+`},
+	{name: "offset pointer in R7", source: `
+        LDO R7, 0x01, R0
+`},
+	{name: "recursive factorial", source: `
+        MLR 0x05, R2
+        SPR R2
+        CLL fact
+        DRP 1
+        MRR R0, R7
+        HLT
+fact:   SPL 0x00        ; Local for n - 1
+        LDF -10, R2     ; n
+        MLR 0x01, R0
+        MLR 0x01, R3
+        CMP R2, R3
+        JLS done
+        SBI R2, 0x01
+        STF R0, 0
+        LDF 0, R4
+        SPR R4
+        CLL fact
+        DRP 1
+        LDF -10, R2
+        MUL R0, R2
+done:   RET
+`},
+	{name: "frame out of bounds", source: `
+        SPL 0x2A
+        STF R1, 1
+        HLT
+`},
+	{name: "drop underflow", source: `
+        SPL 0x2A
+        DRP 2
+        HLT
+`},
+	{name: "word pair in R7", source: `
+        INW R7
+        HLT
+`},
+	{name: "destination word pair in R7", source: `
+        ADW R7, R2, R4
+        HLT
+`},
+	{name: "IP out of bounds", source: `
+        JMP end
+        .org 0xFFFC
+end:    MLR 0x01, R2
+        NOP
+`},
+	{name: "hello world example", image: exampleImage("hello_world.geb")},
+	{name: "invalid instruction example", image: exampleImage("invalid_instruction.geb")},
+	{name: "invalid register", image: []uint8{processor.Inc, 0x09}},
+}
+
+func exampleImage(name string) []uint8 {
+	image, err := ioutil.ReadFile(filepath.Join("..", "examples", name))
+	if err != nil {
+		panic(err)
+	}
+	return image
+}
+
+func (p program) executable(t *testing.T) *executable.Executable {
+	t.Helper()
+	if p.source == "" {
+		return executable.Legacy(p.image)
+	}
+	assembled, err := assembler.Assemble(p.name+".asm", []byte(p.source))
+	if err != nil {
+		t.Fatalf("%s: %s", p.name, err)
+	}
+	return assembled.Executable()
+}
+
+// interpret runs e in the interpreter and returns its output, errors and
+// exit status.
+func interpret(e *executable.Executable, input string) (string, string, int) {
+	var out, errs bytes.Buffer
+	m := memory.New()
+	e.Load(m)
+	w, ew := bufio.NewWriter(&out), bufio.NewWriter(&errs)
+	p := processor.New(m, bufio.NewReader(strings.NewReader(input)), w, ew)
+	p.SetInstructionPointer(e.Entry)
+	status := p.Run()
+	if status != 0 {
+		status = processor.ExitStatus(p.Errors())
+	}
+	s := p.Snapshot()
+	fmt.Fprintf(w, stateFormat, s.Registers, s.InstructionPointer, s.StackPointer, s.StackSize,
+		processor.FlagString(s.Flags), s.InterruptsEnabled, sha256.Sum256(s.Memory))
+	w.Flush()
+	ew.Flush()
+	return out.String(), errs.String(), status
+}
+
+const harnessSource = `package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/scottmcleodjr/gebvm/aot"
+	"github.com/scottmcleodjr/gebvm/processor"
+%s)
+
+var programs = []func(*aot.Machine) int{%s}
+
+func main() {
+	i, _ := strconv.Atoi(os.Args[1])
+	w, ew := bufio.NewWriter(os.Stdout), bufio.NewWriter(os.Stderr)
+	m := aot.NewMachine(bufio.NewReader(os.Stdin), w, ew)
+	status := programs[i](m)
+	fmt.Fprintf(w, %q, m.R, m.IP, m.SP, m.StackSize, processor.FlagString(m.Flags), m.InterruptsEnabled, sha256.Sum256(m.Memory[:]))
+	w.Flush()
+	ew.Flush()
+	os.Exit(status)
+}
+`
+
+// TestMatchesInterpreter translates each program into a package, builds
+// them into one binary, and compares each run with the interpreter.
+func TestMatchesInterpreter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	goMod := fmt.Sprintf("module aottest\n\ngo 1.15\n\nrequire github.com/scottmcleodjr/gebvm v0.0.0\n\nreplace github.com/scottmcleodjr/gebvm => %s\n", root)
+	write := func(name string, data []byte) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", []byte(goMod))
+
+	var imports, runs strings.Builder
+	for i, p := range programs {
+		pkg := fmt.Sprintf("p%d", i)
+		source, err := aot.Translate(p.executable(t), aot.Options{Package: pkg})
+		if err != nil {
+			t.Fatalf("%s: %s", p.name, err)
+		}
+		write(filepath.Join(pkg, "program.go"), source)
+		fmt.Fprintf(&imports, "\t%q\n", "aottest/"+pkg)
+		fmt.Fprintf(&runs, "%s.Run, ", pkg)
+	}
+	write("main.go", []byte(fmt.Sprintf(harnessSource, imports.String(), runs.String(), stateFormat)))
+
+	build := exec.Command(goTool, "build", "-o", "harness", ".")
+	build.Dir = dir
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building generated code: %s\n%s", err, out)
+	}
+	vet := exec.Command(goTool, "vet", "./...")
+	vet.Dir = dir
+	if out, err := vet.CombinedOutput(); err != nil {
+		t.Fatalf("vetting generated code: %s\n%s", err, out)
+	}
+
+	for i, p := range programs {
+		expectedOut, expectedErrs, expectedStatus := interpret(p.executable(t), p.input)
+
+		var out, errs bytes.Buffer
+		run := exec.Command(filepath.Join(dir, "harness"), fmt.Sprint(i))
+		run.Stdin = strings.NewReader(p.input)
+		run.Stdout, run.Stderr = &out, &errs
+		status := 0
+		if err := run.Run(); err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatalf("%s: %s", p.name, err)
+			}
+			status = exitErr.ExitCode()
+		}
+
+		if out.String() != expectedOut {
+			t.Errorf("%s: got output\n%q\nwant\n%q", p.name, out.String(), expectedOut)
+		}
+		if errs.String() != expectedErrs {
+			t.Errorf("%s: got errors\n%q\nwant\n%q", p.name, errs.String(), expectedErrs)
+		}
+		if status != expectedStatus {
+			t.Errorf("%s: got status %d, want %d", p.name, status, expectedStatus)
+		}
+	}
+}
+
+func TestTranslateBlocks(t *testing.T) {
+	assembled, err := assembler.Assemble("blocks.asm", []byte(`
+        MLR 0x07, R7
+loop:   DIV R2, R7
+        MLM 0x2A, R2
+        JNE R2, loop
+        HLT
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := aot.Translate(assembled.Executable(), aot.Options{Package: "blocks", Source: "blocks.geb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"// Code generated by gebvm aot from blocks.geb. DO NOT EDIT.",
+		"package blocks",
+		"m.MarkCode(0x0000, 14)",
+		"\t\t\tfallthrough\n\t\tcase 0x0003:\n",
+		"if m.R[7] == 0 {\n\t\t\t\treturn m.Interpret(0x0003)",
+		"m.Write(m.Pointer(2), 0x2A)\n\t\t\tif m.Modified {\n\t\t\t\treturn m.Interpret(0x0009)",
+		"if m.R[0] != m.R[2] {\n\t\t\t\tip = 0x0003\n\t\t\t\tcontinue",
+		"case 0x000D:\n\t\t\t// 000D: HLT\n\t\t\treturn m.Halt(0x000E)",
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Errorf("got\n%s\nwant it to contain %q", source, expected)
+		}
+	}
+	if strings.Contains(string(source), "func main()") {
+		t.Errorf("got a main function in a package that is not main")
+	}
+}
+
+func TestTranslateRejectsNewerISA(t *testing.T) {
+	e := executable.Legacy([]uint8{processor.Halt})
+	e.ISAVersion = processor.ISAVersion + 1
+	if _, err := aot.Translate(e, aot.Options{}); err == nil {
+		t.Error("got no error, want an instruction set version error")
+	}
+}
@@ -0,0 +1,393 @@
+package aot
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+// Machine is the state of a translated program.  Generated code executes
+// instructions directly on it, and hands it to the interpreter with
+// Interpret for anything it does not translate.  The methods match the
+// processor's instructions, but never fault: generated code calls
+// Interpret before any instruction that would.
+type Machine struct {
+	R                 [processor.RegisterCount]uint8
+	IP                uint16 // Address of the next instruction once the program stops
+	SP                uint16
+	StackSize         uint8
+	Flags             uint8
+	InterruptsEnabled bool
+	Memory            [memory.MemorySize]uint8
+	Modified          bool    // Set when translated code is written
+	Errors            []error // Execution errors from the interpreter
+
+	code        [memory.MemorySize]bool // Addresses of translated code
+	reader      *bufio.Reader
+	writer      *bufio.Writer
+	errorWriter *bufio.Writer
+	inputErr    error // Error from the last ReadInput
+}
+
+// NewMachine returns a Machine with empty memory that reads input from r,
+// writes output to w and writes execution errors to ew, like a Processor.
+func NewMachine(r *bufio.Reader, w, ew *bufio.Writer) *Machine {
+	return &Machine{
+		SP:          processor.StackStart,
+		reader:      r,
+		writer:      w,
+		errorWriter: ew,
+	}
+}
+
+// Load copies data into memory at address.
+func (m *Machine) Load(address uint16, data []uint8) {
+	copy(m.Memory[address:], data)
+}
+
+// MarkCode marks size bytes from address as translated code.  Writes to
+// them set Modified.
+func (m *Machine) MarkCode(address uint16, size int) {
+	for i := 0; i < size; i++ {
+		m.code[int(address)+i] = true
+	}
+}
+
+// Pointer returns the address or 16-bit value held in register and the
+// register after it.
+func (m *Machine) Pointer(register uint8) uint16 {
+	return uint16(m.R[register])<<8 + uint16(m.R[register+1])
+}
+
+// SetPair stores value in register and the register after it.
+func (m *Machine) SetPair(register uint8, value uint16) {
+	m.R[register] = uint8(value >> 8)
+	m.R[register+1] = uint8(value)
+}
+
+func (m *Machine) Write(address uint16, value uint8) {
+	m.Memory[address] = value
+	if m.code[address] {
+		m.Modified = true
+	}
+}
+
+// SetFlags sets zero and negative from result, and carry and overflow to
+// the given values.
+func (m *Machine) SetFlags(result uint8, carry, overflow bool) {
+	m.Flags = 0
+	if result == 0 {
+		m.Flags |= processor.FlagZero
+	}
+	if result&0x80 != 0 {
+		m.Flags |= processor.FlagNegative
+	}
+	if carry {
+		m.Flags |= processor.FlagCarry
+	}
+	if overflow {
+		m.Flags |= processor.FlagOverflow
+	}
+}
+
+// SetWordFlags sets the flags for a 16-bit result.
+func (m *Machine) SetWordFlags(result uint16, carry, overflow bool) {
+	m.SetFlags(uint8(result>>8), carry, overflow)
+	if result&0xFF != 0 {
+		m.Flags &^= processor.FlagZero
+	}
+}
+
+func (m *Machine) Zero() bool {
+	return m.Flags&processor.FlagZero != 0
+}
+
+func (m *Machine) Carry() bool {
+	return m.Flags&processor.FlagCarry != 0
+}
+
+func (m *Machine) Overflow() bool {
+	return m.Flags&processor.FlagOverflow != 0
+}
+
+func (m *Machine) Negative() bool {
+	return m.Flags&processor.FlagNegative != 0
+}
+
+// Logic stores the result of LND, LOR, LXR or LBC.
+func (m *Machine) Logic(result uint8) {
+	m.R[0] = result
+	m.SetFlags(result, false, false)
+}
+
+func (m *Machine) ShiftLeft(value, distance uint8) {
+	result := value << distance
+	carry := distance >= 1 && distance <= 8 && value&(1<<(8-distance)) != 0
+	m.R[0] = result
+	m.SetFlags(result, carry, false)
+}
+
+func (m *Machine) ShiftRight(value, distance uint8) {
+	result := value >> distance
+	carry := distance >= 1 && distance <= 8 && value&(1<<(distance-1)) != 0
+	m.R[0] = result
+	m.SetFlags(result, carry, false)
+}
+
+func (m *Machine) ArithShiftRight(value, distance uint8) {
+	result := uint8(int8(value) >> distance)
+	lastShifted := distance
+	if lastShifted > 8 {
+		lastShifted = 8
+	}
+	carry := distance >= 1 && (int8(value)>>(lastShifted-1))&1 != 0
+	m.R[0] = result
+	m.SetFlags(result, carry, false)
+}
+
+func (m *Machine) Inc(value uint8) uint8 {
+	m.SetFlags(value+1, m.Carry(), value == 0x7F)
+	return value + 1
+}
+
+func (m *Machine) Dec(value uint8) uint8 {
+	m.SetFlags(value-1, m.Carry(), value == 0x80)
+	return value - 1
+}
+
+func (m *Machine) Add(left, right uint8) {
+	sum := left + right
+	m.R[0] = sum
+	m.SetFlags(sum, uint16(left)+uint16(right) > 0xFF, (left^sum)&(right^sum)&0x80 != 0)
+}
+
+// Subtract returns left - right and sets the flags, for SUB and CMP.
+func (m *Machine) Subtract(left, right uint8) uint8 {
+	diff := left - right
+	m.SetFlags(diff, left < right, (left^right)&(left^diff)&0x80 != 0)
+	return diff
+}
+
+func (m *Machine) Multiply(left, right uint8) {
+	wideProduct := uint16(left) * uint16(right)
+	product := uint8(wideProduct)
+	m.R[0] = product
+	m.SetFlags(product, wideProduct > 0xFF, wideProduct > 0xFF)
+}
+
+// Divide requires a nonzero right.
+func (m *Machine) Divide(left, right uint8) {
+	quotient := left / right
+	m.R[0] = quotient
+	m.SetFlags(quotient, false, false)
+}
+
+func (m *Machine) IncWord(register uint8) {
+	value := m.Pointer(register)
+	m.SetPair(register, value+1)
+	m.SetWordFlags(value+1, m.Carry(), value == 0x7FFF)
+}
+
+func (m *Machine) DecWord(register uint8) {
+	value := m.Pointer(register)
+	m.SetPair(register, value-1)
+	m.SetWordFlags(value-1, m.Carry(), value == 0x8000)
+}
+
+func (m *Machine) AddWord(left, right uint16) {
+	sum := left + right
+	m.SetPair(0, sum)
+	m.SetWordFlags(sum, uint32(left)+uint32(right) > 0xFFFF, (left^sum)&(right^sum)&0x8000 != 0)
+}
+
+// SubtractWord returns left - right and sets the flags, for SBW and CPW.
+func (m *Machine) SubtractWord(left, right uint16) uint16 {
+	diff := left - right
+	m.SetWordFlags(diff, left < right, (left^right)&(left^diff)&0x8000 != 0)
+	return diff
+}
+
+func (m *Machine) SignedMultiply(left, right uint8) {
+	wideProduct := int16(int8(left)) * int16(int8(right))
+	product := uint8(wideProduct)
+	m.R[0] = product
+	outOfRange := wideProduct < -128 || wideProduct > 127
+	m.SetFlags(product, outOfRange, outOfRange)
+}
+
+// SignedDivide requires a nonzero right.
+func (m *Machine) SignedDivide(left, right uint8) {
+	quotient := uint8(int8(left) / int8(right))
+	m.R[0] = quotient
+	m.SetFlags(quotient, false, left == 0x80 && right == 0xFF)
+}
+
+// SignedRemainder requires a nonzero right.
+func (m *Machine) SignedRemainder(left, right uint8) {
+	remainder := uint8(int8(left) % int8(right))
+	m.R[0] = remainder
+	m.SetFlags(remainder, false, false)
+}
+
+// Modulo requires a nonzero right.
+func (m *Machine) Modulo(left, right uint8) {
+	remainder := left % right
+	m.R[0] = remainder
+	m.SetFlags(remainder, false, false)
+}
+
+func (m *Machine) SignExtend(value uint8, register uint8) {
+	result := uint16(int16(int8(value)))
+	m.SetPair(register, result)
+	m.SetWordFlags(result, false, false)
+}
+
+// CanPush reports whether count values can be pushed without a stack
+// overflow.
+func (m *Machine) CanPush(count int) bool {
+	return int(m.SP)+count <= int(processor.StackLimit)
+}
+
+// Push requires CanPush(1).
+func (m *Machine) Push(value uint8) {
+	m.Write(m.SP, value)
+	m.SP++
+	m.StackSize++
+}
+
+func (m *Machine) Pop() uint8 {
+	m.SP--
+	m.StackSize--
+	return m.Memory[m.SP]
+}
+
+// FrameAddress returns the address at offset from the frame pointer, and
+// false if it is not on the stack, which is an error for LDF and STF.
+func (m *Machine) FrameAddress(offset int8) (uint16, bool) {
+	address := int(m.SP) - int(m.StackSize) + int(offset)
+	return uint16(address), address >= int(processor.StackStart) && address < int(m.SP)
+}
+
+// Drop removes count values from the current frame, which requires
+// count <= StackSize.
+func (m *Machine) Drop(count uint8) {
+	m.SP -= uint16(count)
+	m.StackSize -= count
+}
+
+// Call saves the frame for CLL, which requires CanPush(9).
+func (m *Machine) Call(returnAddress uint16) {
+	m.Push(m.StackSize)
+	for r := uint8(2); r < 8; r++ {
+		m.Push(m.R[r])
+	}
+	m.Push(uint8(returnAddress >> 8))
+	m.Push(uint8(returnAddress))
+	m.StackSize = 0
+}
+
+// Return restores the frame for RET and returns the return address.
+func (m *Machine) Return() uint16 {
+	for i := uint8(0); i < m.StackSize; i++ {
+		m.Pop() // Current stack falls out of scope
+	}
+	ip := uint16(m.Pop())
+	ip += uint16(m.Pop()) << 8
+	for r := uint8(7); r > 1; r-- {
+		m.R[r] = m.Pop()
+	}
+	m.StackSize = m.Pop()
+	return ip
+}
+
+// ReturnInterrupt restores the frame for RTI and returns the return
+// address.
+func (m *Machine) ReturnInterrupt() uint16 {
+	for i := uint8(0); i < m.StackSize; i++ {
+		m.Pop() // Current stack falls out of scope
+	}
+	ip := uint16(m.Pop())
+	ip += uint16(m.Pop()) << 8
+	m.Flags = m.Pop()
+	for r := int(processor.RegisterCount) - 1; r >= 0; r-- {
+		m.R[r] = m.Pop()
+	}
+	m.StackSize = m.Pop()
+	m.InterruptsEnabled = true
+	return ip
+}
+
+func (m *Machine) Print(address uint16, length uint8) {
+	for i := uint16(0); i < uint16(length); i++ {
+		fmt.Fprintf(m.writer, "%c", m.Memory[address+i])
+	}
+	m.writer.Flush()
+}
+
+// ReadInput reads a byte for RIN.  It returns false if the read failed,
+// and the interpreter reports the error when the instruction is
+// interpreted.
+func (m *Machine) ReadInput() (uint8, bool) {
+	c, err := m.reader.ReadByte()
+	if err != nil {
+		m.inputErr = err
+		return 0, false
+	}
+	return c, true
+}
+
+// Halt stops the program at HLT, where next is the address after it.
+func (m *Machine) Halt(next uint16) int {
+	m.IP = next
+	return 0
+}
+
+// Interpret runs the program in the interpreter from ip until it stops,
+// copies the final state back, and returns the exit status.
+func (m *Machine) Interpret(ip uint16) int {
+	reader := m.reader
+	if m.inputErr != nil {
+		// The interpreter reads again and sees the same error
+		reader = bufio.NewReader(errorReader{m.inputErr})
+	}
+	p := processor.New(memory.New(), reader, m.writer, m.errorWriter)
+	err := p.Restore(&processor.Snapshot{
+		Registers:          m.R,
+		InstructionPointer: ip,
+		StackPointer:       m.SP,
+		StackSize:          m.StackSize,
+		Flags:              m.Flags,
+		InterruptsEnabled:  m.InterruptsEnabled,
+		Memory:             m.Memory[:],
+	})
+	if err != nil {
+		fmt.Fprintf(m.errorWriter, "** %s\n", err)
+		m.errorWriter.Flush()
+		return 1
+	}
+
+	status := p.Run()
+	s := p.Snapshot()
+	m.R = s.Registers
+	m.IP = s.InstructionPointer
+	m.SP = s.StackPointer
+	m.StackSize = s.StackSize
+	m.Flags = s.Flags
+	m.InterruptsEnabled = s.InterruptsEnabled
+	copy(m.Memory[:], s.Memory)
+	m.Errors = p.Errors()
+	if status != 0 {
+		return processor.ExitStatus(m.Errors)
+	}
+	return 0
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
 
+	"github.com/scottmcleodjr/gebvm/executable"
 	"github.com/scottmcleodjr/gebvm/memory"
-	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/symbols"
 )
 
 const (
@@ -15,6 +15,35 @@ const (
 	
   Example: ./gebvm hello_world.geb
 
+Run options (before the file):
+
+  --trace <text|json>    Write a record of each executed instruction
+  --trace-file <file>    Write trace records to a file instead of stderr
+  --profile              Write a report of where instructions were executed
+  --pprof <file>         Write a profile for go tool pprof
+  --coverage <file>      Write a coverage report of executed instructions and branches
+  --coverage-format <f>  Coverage report format, lcov or html (default lcov)
+  --max-steps <n>        Stop with an error after n instructions
+  --timeout <duration>   Stop with an error after a duration, such as 5s
+  --protect <region>     Protect memory, such as 0x0000-0x00FF:ro (repeatable)
+  --stack-guard          Only allow stack operations to use the stack
+  --banks <window>       Map banks of RAM to a window, such as 0x8000-0xBFFF:8
+  --bank-select <addr>   Map the bank-select port to an address
+  --bank-image <file>    Load a file across the banks, starting at bank 0
+  --symbols <file>       Read debug symbols (default: the file with a .sym extension)
+  --snapshot <file>      Write a machine snapshot when execution stops
+  --snapshot-on <list>   Events that write a snapshot (default halt,signal,error)
+
+Other commands:
+
+  gebvm asm [-o output] [-exe] [-sym] <source>
+                                    Assemble source into a bytecode file
+  gebvm disasm [-follow] <file>     Write the disassembly of a bytecode file
+  gebvm debug <file>                Debug a bytecode file interactively
+  gebvm resume <snapshot>           Continue execution from a snapshot
+  gebvm aot [-o output] [-package name] <file>
+                                    Translate a bytecode file into Go source
+
 `
 )
 
@@ -24,26 +53,64 @@ func main() {
 		os.Exit(0)
 	}
 
-	filename := os.Args[1]
-	program, err := ioutil.ReadFile(filename)
+	switch os.Args[1] {
+	case "asm":
+		os.Exit(asmCommand(os.Args[2:]))
+	case "disasm":
+		os.Exit(disasmCommand(os.Args[2:]))
+	case "debug":
+		os.Exit(debugCommand(os.Args[2:]))
+	case "resume":
+		os.Exit(resumeCommand(os.Args[2:]))
+	case "aot":
+		os.Exit(aotCommand(os.Args[2:]))
+	}
+
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// loadExecutable reads a bytecode file.  Files are either executables
+// or legacy raw bytecode.
+func loadExecutable(filename string) (*executable.Executable, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input file: %s", err)
+	}
+	return executable.Decode(data)
+}
+
+// loadProgram returns memory holding the program from a bytecode file
+// and the executable it was loaded from.
+func loadProgram(filename string) (*memory.Memory, *executable.Executable, error) {
+	e, err := loadExecutable(filename)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error reading input file: %s", err)
-		os.Exit(1)
+		return nil, nil, err
 	}
 
-	m := memory.Memory{}
-	err = m.LoadProgram(program)
+	m := memory.New()
+	err = e.Load(m)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s", err)
-		os.Exit(1)
+		return nil, nil, err
 	}
+	return m, e, nil
+}
 
-	proc := processor.New(
-		&m,
-		bufio.NewReader(os.Stdin),
-		bufio.NewWriter(os.Stdout),
-		bufio.NewWriter(os.Stderr),
-	)
-	status := proc.Run()
-	os.Exit(status)
+// loadSymbols reads the symbol file for a bytecode file.  It returns nil
+// without an error if there is no symbol file.
+func loadSymbols(filename string) (*symbols.Table, error) {
+	sidecar := symbols.SidecarName(filename)
+	if _, err := os.Stat(sidecar); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return readSymbols(sidecar)
+}
+
+// readSymbols reads a symbol file.
+func readSymbols(filename string) (*symbols.Table, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading symbol file: %s", err)
+	}
+	defer f.Close()
+	return symbols.Read(f)
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/scottmcleodjr/gebvm/assembler"
+	"github.com/scottmcleodjr/gebvm/symbols"
+)
+
+const (
+	asmHelpText string = `Provide the assembly source file as an argument.
+
+  Example: ./gebvm asm -o hello_world.geb hello_world.asm
+
+`
+)
+
+// asmCommand assembles a source file and writes the bytecode file.
+func asmCommand(args []string) int {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	output := flags.String("o", "", "bytecode output file (default: source file with .geb extension)")
+	sym := flags.Bool("sym", false, "write a symbol file next to the output for run, debug and disasm")
+	exe := flags.Bool("exe", false, "write an executable with a header, entry address and segments instead of raw bytecode")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, asmHelpText)
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading input file: %s\n", err)
+		return 1
+	}
+
+	program, err := assembler.Assemble(filename, source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".geb"
+	}
+	data := program.Image
+	if *exe {
+		data, err = program.Executable().Encode()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+	} else if program.Entry != 0x0000 {
+		fmt.Fprintf(os.Stderr, "raw bytecode always starts at 0x0000, use -exe for the .entry address\n")
+		return 1
+	}
+	err = ioutil.WriteFile(*output, data, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing output file: %s\n", err)
+		return 1
+	}
+
+	if *sym {
+		err = writeSymbols(symbols.SidecarName(*output), program.Symbols)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing symbol file: %s\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+func writeSymbols(filename string, table *symbols.Table) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = table.Write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
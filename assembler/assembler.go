@@ -0,0 +1,532 @@
+// Package assembler translates GebVM assembly source into bytecode.
+//
+// Each line holds an optional label, followed by an optional instruction
+// or directive and its comma separated operands.  Comments begin with a
+// semicolon and run to the end of the line.
+//
+//	start:  PNT message, 14      ; Print the message
+//	        HLT
+//	message:
+//	        .string "Hello, World!\n"
+//
+// Instructions use the mnemonics from the processor instruction set.
+// Registers are written R0 through R7.  Literals can be hex (0x2A),
+// decimal (42 or -1) or a quoted character ('*').  Addresses can be a
+// literal or a label.  The supported directives are:
+//
+//	.org   address        Continue output at address
+//	.byte  literal, ...   Output literal bytes
+//	.string "text", ...   Output the bytes of quoted strings
+//	.entry address        Start execution at address
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/symbols"
+)
+
+// Error is an assembly error at a position in the source.
+type Error struct {
+	Filename string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Message)
+}
+
+// ErrorList is the list of every error found while assembling.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Program is the output of a successful assembly.
+type Program struct {
+	Image    []uint8              // Bytecode to be loaded at address 0x0000
+	Labels   map[string]uint16    // Address of each label
+	Entry    uint16               // Address of the first instruction
+	Segments []executable.Segment // Each contiguous run of output bytes
+	Symbols  *symbols.Table       // Source line of each statement and the labels
+}
+
+// Executable returns the program as an executable with a segment for
+// each contiguous run of output.
+func (p *Program) Executable() *executable.Executable {
+	return &executable.Executable{
+		ISAVersion: processor.ISAVersion,
+		Entry:      p.Entry,
+		Segments:   p.Segments,
+	}
+}
+
+// statement is a single line of source after the first pass.
+type statement struct {
+	line     int
+	address  uint16
+	name     token   // Mnemonic or directive
+	operands []token // One token per operand
+	size     int
+}
+
+type assembler struct {
+	filename   string
+	statements []*statement
+	labels     map[string]uint16
+	entry      *statement // .entry directive, if any
+	written    []bool     // addresses with output
+	errors     ErrorList
+}
+
+// Assemble translates source into bytecode.  The filename is only used
+// in error messages.  On failure the returned error is an ErrorList.
+func Assemble(filename string, source []byte) (*Program, error) {
+	a := &assembler{
+		filename: filename,
+		labels:   map[string]uint16{},
+	}
+	a.firstPass(string(source))
+	if len(a.errors) > 0 {
+		return nil, a.errors
+	}
+	image := a.secondPass()
+	var entry uint16
+	if a.entry != nil {
+		entry, _ = a.addressValue(a.entry.line, a.entry.operands[0])
+	}
+	if len(a.errors) > 0 {
+		return nil, a.errors
+	}
+	return &Program{
+		Image:    image,
+		Labels:   a.labels,
+		Entry:    entry,
+		Segments: a.segments(image),
+		Symbols:  a.symbols(),
+	}, nil
+}
+
+func (a *assembler) errorf(line, column int, format string, args ...interface{}) {
+	a.errors = append(a.errors, &Error{
+		Filename: a.filename,
+		Line:     line,
+		Column:   column,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// firstPass parses every line, assigns addresses and records labels.
+func (a *assembler) firstPass(source string) {
+	address := 0
+	for i, text := range strings.Split(source, "\n") {
+		line := i + 1
+		tokens, err := tokenize(text)
+		if err != nil {
+			a.errorf(line, err.Column, "%s", err.Message)
+			continue
+		}
+
+		for len(tokens) >= 2 && tokens[0].kind == tokenIdent && tokens[1].kind == tokenColon {
+			a.defineLabel(line, tokens[0], address)
+			tokens = tokens[2:]
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		if tokens[0].kind != tokenIdent {
+			a.errorf(line, tokens[0].column, "expected instruction or directive, found %s", tokens[0].text)
+			continue
+		}
+
+		s := &statement{line: line, name: tokens[0]}
+		if !a.parseOperands(s, tokens[1:]) {
+			continue
+		}
+
+		if strings.EqualFold(s.name.text, ".entry") {
+			a.entryDirective(s)
+			continue
+		}
+		if strings.EqualFold(s.name.text, ".org") {
+			org, ok := a.orgAddress(s)
+			if ok {
+				address = org
+			}
+			continue
+		}
+
+		s.address = uint16(address)
+		s.size = a.statementSize(s)
+		if s.size < 0 {
+			continue
+		}
+		if address+s.size > memory.MemorySize {
+			a.errorf(line, s.name.column, "output exceeds address 0x%04X", memory.MemorySize-1)
+			return
+		}
+		address += s.size
+		a.statements = append(a.statements, s)
+	}
+}
+
+func (a *assembler) defineLabel(line int, name token, address int) {
+	if _, isRegister := parseRegister(name.text); isRegister || strings.HasPrefix(name.text, ".") {
+		a.errorf(line, name.column, "invalid label name %s", name.text)
+		return
+	}
+	if _, found := processor.LookupMnemonic(name.text); found {
+		a.errorf(line, name.column, "invalid label name %s", name.text)
+		return
+	}
+	if _, found := a.labels[name.text]; found {
+		a.errorf(line, name.column, "label %s already defined", name.text)
+		return
+	}
+	if address >= memory.MemorySize {
+		a.errorf(line, name.column, "label %s is beyond address 0x%04X", name.text, memory.MemorySize-1)
+		return
+	}
+	a.labels[name.text] = uint16(address)
+}
+
+// parseOperands checks that operands are single tokens separated by commas.
+func (a *assembler) parseOperands(s *statement, tokens []token) bool {
+	for i, t := range tokens {
+		expectComma := i%2 == 1
+		if expectComma != (t.kind == tokenComma) || t.kind == tokenColon {
+			a.errorf(s.line, t.column, "unexpected %s", t.text)
+			return false
+		}
+		if !expectComma {
+			s.operands = append(s.operands, t)
+		}
+	}
+	if len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenComma {
+		a.errorf(s.line, tokens[len(tokens)-1].column, "missing operand after comma")
+		return false
+	}
+	return true
+}
+
+func (a *assembler) orgAddress(s *statement) (int, bool) {
+	if len(s.operands) != 1 || s.operands[0].kind != tokenNumber {
+		a.errorf(s.line, s.name.column, ".org requires a single numeric address")
+		return 0, false
+	}
+	value, err := parseNumber(s.operands[0].text)
+	if err != nil || value < 0 || value >= memory.MemorySize {
+		a.errorf(s.line, s.operands[0].column, "invalid address %s", s.operands[0].text)
+		return 0, false
+	}
+	return value, true
+}
+
+func (a *assembler) entryDirective(s *statement) {
+	if a.entry != nil {
+		a.errorf(s.line, s.name.column, ".entry already defined on line %d", a.entry.line)
+		return
+	}
+	if len(s.operands) != 1 {
+		a.errorf(s.line, s.name.column, ".entry requires a single address")
+		return
+	}
+	a.entry = s
+}
+
+// statementSize returns the number of bytes output by a statement,
+// or -1 if the statement is invalid.
+func (a *assembler) statementSize(s *statement) int {
+	switch strings.ToLower(s.name.text) {
+	case ".byte":
+		if len(s.operands) == 0 {
+			a.errorf(s.line, s.name.column, ".byte requires at least one operand")
+			return -1
+		}
+		return len(s.operands)
+	case ".string":
+		if len(s.operands) == 0 {
+			a.errorf(s.line, s.name.column, ".string requires at least one operand")
+			return -1
+		}
+		size := 0
+		for _, operand := range s.operands {
+			value, ok := a.stringValue(s.line, operand)
+			if !ok {
+				return -1
+			}
+			size += len(value)
+		}
+		return size
+	}
+
+	if strings.HasPrefix(s.name.text, ".") {
+		a.errorf(s.line, s.name.column, "unknown directive %s", s.name.text)
+		return -1
+	}
+	forms := processor.Forms(s.name.text)
+	if len(forms) == 0 {
+		a.errorf(s.line, s.name.column, "unknown instruction %s", s.name.text)
+		return -1
+	}
+	info, found := processor.LookupForm(s.name.text, len(s.operands))
+	if !found {
+		counts := make([]string, len(forms))
+		for i, form := range forms {
+			counts[i] = strconv.Itoa(len(form.Operands))
+		}
+		a.errorf(s.line, s.name.column, "%s takes %s operands, found %d",
+			forms[0].Mnemonic, strings.Join(counts, " or "), len(s.operands))
+		return -1
+	}
+	return int(info.Size())
+}
+
+// secondPass encodes every statement now that all labels are known.
+func (a *assembler) secondPass() []uint8 {
+	var image []uint8
+	a.written = make([]bool, memory.MemorySize)
+	for _, s := range a.statements {
+		encoded := a.encode(s)
+		if encoded == nil {
+			continue
+		}
+		end := int(s.address) + len(encoded)
+		if end > len(image) {
+			image = append(image, make([]uint8, end-len(image))...)
+		}
+		for i, value := range encoded {
+			address := int(s.address) + i
+			if a.written[address] {
+				a.errorf(s.line, s.name.column, "output overlaps address 0x%04X", address)
+				break
+			}
+			a.written[address] = true
+			image[address] = value
+		}
+	}
+	return image
+}
+
+// symbols returns the symbol table for the statements and labels.
+func (a *assembler) symbols() *symbols.Table {
+	lines := make([]symbols.Line, 0, len(a.statements))
+	for _, s := range a.statements {
+		if s.size == 0 {
+			continue
+		}
+		lines = append(lines, symbols.Line{
+			Address: s.address,
+			Size:    uint16(s.size),
+			File:    a.filename,
+			Line:    s.line,
+			Data:    strings.HasPrefix(s.name.text, "."),
+		})
+	}
+	return symbols.New(lines, a.labels)
+}
+
+// segments returns a segment for each contiguous run of output bytes.
+func (a *assembler) segments(image []uint8) []executable.Segment {
+	var segments []executable.Segment
+	start := -1
+	for address := 0; address <= len(image); address++ {
+		if address < len(image) && a.written[address] {
+			if start < 0 {
+				start = address
+			}
+			continue
+		}
+		if start >= 0 {
+			data := make([]uint8, address-start)
+			copy(data, image[start:address])
+			segments = append(segments, executable.Segment{Address: uint16(start), Data: data})
+			start = -1
+		}
+	}
+	return segments
+}
+
+func (a *assembler) encode(s *statement) []uint8 {
+	out := make([]uint8, 0, s.size)
+	switch strings.ToLower(s.name.text) {
+	case ".byte":
+		for _, operand := range s.operands {
+			value, ok := a.literalValue(s.line, operand)
+			if !ok {
+				return nil
+			}
+			out = append(out, value)
+		}
+		return out
+	case ".string":
+		for _, operand := range s.operands {
+			value, _ := a.stringValue(s.line, operand)
+			out = append(out, value...)
+		}
+		return out
+	}
+
+	info, _ := processor.LookupForm(s.name.text, len(s.operands))
+	out = append(out, info.Opcode)
+	for i, kind := range info.Operands {
+		operand := s.operands[i]
+		switch kind {
+		case processor.OperandRegister:
+			register, ok := parseRegister(operand.text)
+			if !ok || operand.kind != tokenIdent {
+				a.errorf(s.line, operand.column, "expected register, found %s", operand.text)
+				return nil
+			}
+			out = append(out, register)
+		case processor.OperandLiteral:
+			value, ok := a.literalValue(s.line, operand)
+			if !ok {
+				return nil
+			}
+			out = append(out, value)
+		case processor.OperandOffset:
+			value, ok := a.offsetValue(s.line, operand)
+			if !ok {
+				return nil
+			}
+			out = append(out, value)
+		case processor.OperandAddress:
+			value, ok := a.addressValue(s.line, operand)
+			if !ok {
+				return nil
+			}
+			out = append(out, uint8(value>>8), uint8(value))
+		case processor.OperandWord:
+			value, ok := a.wordValue(s.line, operand)
+			if !ok {
+				return nil
+			}
+			out = append(out, uint8(value>>8), uint8(value))
+		}
+	}
+	return out
+}
+
+func (a *assembler) literalValue(line int, operand token) (uint8, bool) {
+	switch operand.kind {
+	case tokenNumber:
+		value, err := parseNumber(operand.text)
+		if err != nil || value < -128 || value > 0xFF {
+			a.errorf(line, operand.column, "invalid byte literal %s", operand.text)
+			return 0, false
+		}
+		return uint8(value), true
+	case tokenChar:
+		value, _, tail, err := strconv.UnquoteChar(operand.text[1:len(operand.text)-1], '\'')
+		if err != nil || tail != "" || value > 0xFF {
+			a.errorf(line, operand.column, "invalid character literal %s", operand.text)
+			return 0, false
+		}
+		return uint8(value), true
+	}
+	a.errorf(line, operand.column, "expected literal, found %s", operand.text)
+	return 0, false
+}
+
+func (a *assembler) offsetValue(line int, operand token) (uint8, bool) {
+	if operand.kind != tokenNumber {
+		a.errorf(line, operand.column, "expected offset, found %s", operand.text)
+		return 0, false
+	}
+	value, err := parseNumber(operand.text)
+	if err != nil || value < -128 || value > 127 {
+		a.errorf(line, operand.column, "invalid offset %s", operand.text)
+		return 0, false
+	}
+	return uint8(value), true
+}
+
+func (a *assembler) addressValue(line int, operand token) (uint16, bool) {
+	switch operand.kind {
+	case tokenNumber:
+		value, err := parseNumber(operand.text)
+		if err != nil || value < 0 || value >= memory.MemorySize {
+			a.errorf(line, operand.column, "invalid address %s", operand.text)
+			return 0, false
+		}
+		return uint16(value), true
+	case tokenIdent:
+		value, found := a.labels[operand.text]
+		if !found {
+			a.errorf(line, operand.column, "undefined label %s", operand.text)
+			return 0, false
+		}
+		return value, true
+	}
+	a.errorf(line, operand.column, "expected address, found %s", operand.text)
+	return 0, false
+}
+
+// wordValue accepts 16-bit literals and labels, so a register pair can
+// be loaded with the address of a label.
+func (a *assembler) wordValue(line int, operand token) (uint16, bool) {
+	switch operand.kind {
+	case tokenNumber:
+		value, err := parseNumber(operand.text)
+		if err != nil || value < -0x8000 || value > 0xFFFF {
+			a.errorf(line, operand.column, "invalid word literal %s", operand.text)
+			return 0, false
+		}
+		return uint16(value), true
+	case tokenIdent:
+		return a.addressValue(line, operand)
+	}
+	a.errorf(line, operand.column, "expected word literal, found %s", operand.text)
+	return 0, false
+}
+
+func (a *assembler) stringValue(line int, operand token) ([]uint8, bool) {
+	if operand.kind != tokenString {
+		a.errorf(line, operand.column, "expected string, found %s", operand.text)
+		return nil, false
+	}
+	value, err := strconv.Unquote(operand.text)
+	if err != nil {
+		a.errorf(line, operand.column, "invalid string literal %s", operand.text)
+		return nil, false
+	}
+	return []uint8(value), true
+}
+
+// parseNumber parses hex literals with a 0x prefix and decimal literals.
+func parseNumber(text string) (int, error) {
+	base := 10
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		base = 16
+		text = text[2:]
+	}
+	value, err := strconv.ParseInt(text, base, 32)
+	return int(value), err
+}
+
+func parseRegister(text string) (uint8, bool) {
+	if len(text) != 2 || (text[0] != 'R' && text[0] != 'r') {
+		return 0, false
+	}
+	register := text[1] - '0'
+	if register >= processor.RegisterCount {
+		return 0, false
+	}
+	return register, true
+}
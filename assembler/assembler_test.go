@@ -0,0 +1,194 @@
+package assembler_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/assembler"
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func assembleAndCheckImage(t *testing.T, source string, expected []uint8) *assembler.Program {
+	t.Helper()
+	program, err := assembler.Assemble("test.asm", []byte(source))
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if !bytes.Equal(program.Image, expected) {
+		t.Errorf("got image % X, want % X", program.Image, expected)
+	}
+	return program
+}
+
+func TestAssembleInstructions(t *testing.T) {
+	assembleAndCheckImage(t, `
+		NOP
+		MLR 0x2A, R1
+		mrr r1, R7    ; Mnemonics and registers are not case sensitive
+		JEQ R2, 0x1234
+		PNT 0xABCD, 14
+		HLT`,
+		[]uint8{
+			processor.Noop,
+			processor.MoveLitReg, 0x2A, 0x01,
+			processor.MoveRegReg, 0x01, 0x07,
+			processor.JumpEqual, 0x02, 0x12, 0x34,
+			processor.Print, 0xAB, 0xCD, 0x0E,
+			processor.Halt,
+		})
+}
+
+func TestAssembleDestinationForms(t *testing.T) {
+	assembleAndCheckImage(t, `
+		ADD R1, R2        ; Stores in R0
+		ADD R1, R2, R3
+		ani r4, 0x0F, R5
+		ADW R2, R4, R6`,
+		[]uint8{
+			processor.Add, 0x01, 0x02,
+			processor.AddDst, 0x01, 0x02, 0x03,
+			processor.LogicalAndLitDst, 0x04, 0x0F, 0x05,
+			processor.AddWordDst, 0x02, 0x04, 0x06,
+		})
+}
+
+func TestAssembleLiterals(t *testing.T) {
+	assembleAndCheckImage(t, `.byte 0x2A, 0XFF, 42, -1, -128, 'A', '\n', '\x7F', ';'`,
+		[]uint8{0x2A, 0xFF, 42, 0xFF, 0x80, 'A', '\n', 0x7F, ';'})
+}
+
+func TestAssembleLabels(t *testing.T) {
+	program := assembleAndCheckImage(t, `
+		start:  JMP end
+		loop:   JNE R1, loop
+		end:    CLL start
+		        MLW loop, R2
+		        MLW -2, R4
+		`,
+		[]uint8{
+			processor.Jump, 0x00, 0x07,
+			processor.JumpNotEqual, 0x01, 0x00, 0x03,
+			processor.Call, 0x00, 0x00,
+			processor.MoveLitWord, 0x00, 0x03, 0x02,
+			processor.MoveLitWord, 0xFF, 0xFE, 0x04,
+		})
+	expected := map[string]uint16{"start": 0x0000, "loop": 0x0003, "end": 0x0007}
+	for label, address := range expected {
+		if program.Labels[label] != address {
+			t.Errorf("got 0x%X for label %s, want 0x%X", program.Labels[label], label, address)
+		}
+	}
+}
+
+func TestAssembleDirectives(t *testing.T) {
+	assembleAndCheckImage(t, `
+		.string "ab", "c"
+		.org 0x0008
+		data: .byte 1, 2
+		.org 0x0004
+		JMP data`,
+		[]uint8{'a', 'b', 'c', 0x00, processor.Jump, 0x00, 0x08, 0x00, 0x01, 0x02})
+}
+
+func TestAssembleSegmentsAndEntry(t *testing.T) {
+	program, err := assembler.Assemble("test.asm", []byte(`
+		        .entry start
+		        .byte 0x01, 0x02
+		        .org 0x0010
+		start:  HLT`))
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if program.Entry != 0x0010 {
+		t.Errorf("got entry 0x%04X, want 0x0010", program.Entry)
+	}
+	expected := []executable.Segment{
+		{Address: 0x0000, Data: []uint8{0x01, 0x02}},
+		{Address: 0x0010, Data: []uint8{processor.Halt}},
+	}
+	if !reflect.DeepEqual(program.Segments, expected) {
+		t.Errorf("got segments %v, want %v", program.Segments, expected)
+	}
+}
+
+func TestAssembleSymbols(t *testing.T) {
+	program, err := assembler.Assemble("test.asm", []byte("start:  NOP\n\n        JMP start"))
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	line, found := program.Symbols.Location(0x0002)
+	if !found || line.File != "test.asm" || line.Line != 3 {
+		t.Errorf("got %+v, %t for 0x0002, want test.asm line 3", line, found)
+	}
+	if label, found := program.Symbols.LabelAt(0x0000); !found || label != "start" {
+		t.Errorf("got %q, %t, want start", label, found)
+	}
+}
+
+func TestAssembleHelloWorldExample(t *testing.T) {
+	source, err := ioutil.ReadFile("../examples/hello_world.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("../examples/hello_world.geb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assembleAndCheckImage(t, string(source), expected)
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		source         string
+		line, column   int
+		messageContent string
+	}{
+		{source: "FOO R1", line: 1, column: 1, messageContent: "unknown instruction FOO"},
+		{source: "\n  MLR 0x2A", line: 2, column: 3, messageContent: "MLR takes 2 operands, found 1"},
+		{source: "ADD R1", line: 1, column: 1, messageContent: "ADD takes 2 or 3 operands, found 1"},
+		{source: "MLR 0x2A, R8", line: 1, column: 11, messageContent: "expected register, found R8"},
+		{source: "MLR 0x100, R1", line: 1, column: 5, messageContent: "invalid byte literal 0x100"},
+		{source: "JMP nowhere", line: 1, column: 5, messageContent: "undefined label nowhere"},
+		{source: "MLW 0x10000, R2", line: 1, column: 5, messageContent: "invalid word literal 0x10000"},
+		{source: "LDF 128, R2", line: 1, column: 5, messageContent: "invalid offset 128"},
+		{source: "a: NOP\na: NOP", line: 2, column: 1, messageContent: "label a already defined"},
+		{source: ".word 1", line: 1, column: 1, messageContent: "unknown directive .word"},
+		{source: ".string \"abc", line: 1, column: 9, messageContent: "unterminated quoted literal"},
+		{source: "NOP\n.org 0x0000\nHLT", line: 3, column: 1, messageContent: "output overlaps address 0x0000"},
+		{source: "INC R1 R2", line: 1, column: 8, messageContent: "unexpected R2"},
+		{source: ".org 0xFFFF\nJMP 0x0000", line: 2, column: 1, messageContent: "output exceeds address 0xFFFF"},
+		{source: ".entry 0x0000\n.entry 0x0001", line: 2, column: 1, messageContent: ".entry already defined on line 1"},
+		{source: ".entry nowhere", line: 1, column: 8, messageContent: "undefined label nowhere"},
+	}
+
+	for _, test := range tests {
+		_, err := assembler.Assemble("test.asm", []byte(test.source))
+		var errs assembler.ErrorList
+		if !errors.As(err, &errs) || len(errs) == 0 {
+			t.Errorf("got %v for %q, want ErrorList", err, test.source)
+			continue
+		}
+		e := errs[0]
+		if e.Line != test.line || e.Column != test.column || e.Message != test.messageContent {
+			t.Errorf("got %q for %q, want %d:%d: %s", e, test.source, test.line, test.column, test.messageContent)
+		}
+		if e.Filename != "test.asm" {
+			t.Errorf("got filename %q, want test.asm", e.Filename)
+		}
+	}
+}
+
+func TestAssembleReportsEveryError(t *testing.T) {
+	_, err := assembler.Assemble("", []byte("FOO\nNOP\nBAR"))
+	errs, _ := err.(assembler.ErrorList)
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2", len(errs))
+	}
+	if errs.Error() != "1:1: unknown instruction FOO\n3:1: unknown instruction BAR" {
+		t.Errorf("got %q", errs.Error())
+	}
+}
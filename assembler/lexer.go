@@ -0,0 +1,81 @@
+package assembler
+
+import "fmt"
+
+type tokenKind uint8
+
+const (
+	tokenIdent  tokenKind = iota // Mnemonics, registers, labels and directives
+	tokenNumber                  // Hex, decimal and negative decimal literals
+	tokenChar                    // Quoted character literals
+	tokenString                  // Quoted string literals
+	tokenComma
+	tokenColon
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tokenize splits a single line of source into tokens.  Everything
+// after a semicolon outside of a quoted literal is a comment.
+func tokenize(line string) ([]token, *Error) {
+	var tokens []token
+	for i := 0; i < len(line); {
+		c := line[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			return tokens, nil
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", column: start + 1})
+			i++
+		case c == ':':
+			tokens = append(tokens, token{kind: tokenColon, text: ":", column: start + 1})
+			i++
+		case c == '\'' || c == '"':
+			i++
+			for i < len(line) && line[i] != c {
+				if line[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(line) {
+				return nil, &Error{Column: start + 1, Message: "unterminated quoted literal"}
+			}
+			i++
+			kind := tokenChar
+			if c == '"' {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: line[start:i], column: start + 1})
+		case isDigit(c) || (c == '-' && i+1 < len(line) && isDigit(line[i+1])):
+			i++
+			for i < len(line) && (isLetter(line[i]) || isDigit(line[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: line[start:i], column: start + 1})
+		case isLetter(c):
+			for i < len(line) && (isLetter(line[i]) || isDigit(line[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: line[start:i], column: start + 1})
+		default:
+			return nil, &Error{Column: start + 1, Message: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/scottmcleodjr/gebvm/bus"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+// bankWindow is a flag.Value for the --banks option.
+type bankWindow struct {
+	start uint16
+	end   uint16
+	count int
+}
+
+func (w *bankWindow) String() string {
+	if w.count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%04X-0x%04X:%d", w.start, w.end, w.count)
+}
+
+func (w *bankWindow) Set(value string) error {
+	var start, end uint16
+	var count int
+	if _, err := fmt.Sscanf(value, "0x%x-0x%x:%d", &start, &end, &count); err != nil {
+		return fmt.Errorf("want start-end:count, such as 0x8000-0xBFFF:8")
+	}
+	if start > end {
+		return fmt.Errorf("window 0x%04X-0x%04X ends before it starts", start, end)
+	}
+	*w = bankWindow{start: start, end: end, count: count}
+	return nil
+}
+
+// newMemory returns plain memory, or a bus with the banks from the bank
+// options and RAM at every other address.
+func newMemory(opts *runOptions) (processor.MemoryDevice, error) {
+	if opts.banks.count == 0 {
+		if *opts.bankSelect != "" || *opts.bankImage != "" {
+			return nil, fmt.Errorf("--bank-select and --bank-image need --banks")
+		}
+		return memory.New(), nil
+	}
+	if *opts.bankSelect == "" {
+		return nil, fmt.Errorf("--banks needs a --bank-select address")
+	}
+	port, err := strconv.ParseUint(*opts.bankSelect, 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bank select address %q", *opts.bankSelect)
+	}
+	window := opts.banks
+	if uint16(port) >= window.start && uint16(port) <= window.end {
+		return nil, fmt.Errorf("bank select address 0x%04X is inside the bank window", port)
+	}
+
+	banked, err := bus.NewBanked(int(window.end-window.start)+1, window.count)
+	if err != nil {
+		return nil, err
+	}
+	if *opts.bankImage != "" {
+		image, err := ioutil.ReadFile(*opts.bankImage)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bank image: %s", err)
+		}
+		if err := banked.LoadImage(image); err != nil {
+			return nil, err
+		}
+	}
+
+	mappings := []bus.Mapping{
+		{Start: window.start, End: window.end, Device: banked},
+		{Start: uint16(port), End: uint16(port), Device: banked.SelectPort()},
+	}
+	start := -1
+	for address := 0; address <= memory.MemorySize; address++ {
+		mapped := address == memory.MemorySize || address == int(port) ||
+			(address >= int(window.start) && address <= int(window.end))
+		if !mapped && start < 0 {
+			start = address
+		}
+		if mapped && start >= 0 {
+			mappings = append(mappings, bus.Mapping{
+				Start:  uint16(start),
+				End:    uint16(address - 1),
+				Device: bus.NewRAM(address - start),
+			})
+			start = -1
+		}
+	}
+	return bus.New(mappings...)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestBankWindowSet(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bankWindow
+		err      string
+	}{
+		{value: "0x8000-0xBFFF:8", expected: bankWindow{start: 0x8000, end: 0xBFFF, count: 8}},
+		{value: "0x8000-0xBFFF", err: "want start-end:count"},
+		{value: "8000-BFFF:8", err: "want start-end:count"},
+		{value: "0xBFFF-0x8000:8", err: "window 0xBFFF-0x8000 ends before it starts"},
+	}
+
+	for _, test := range tests {
+		var w bankWindow
+		err := w.Set(test.value)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v for %q, want %q", err, test.value, test.err)
+			}
+			continue
+		}
+		if err != nil || w != test.expected {
+			t.Errorf("got %+v and %v for %q, want %+v", w, err, test.value, test.expected)
+		}
+	}
+}
+
+func TestNewMemory(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "banks.bin")
+	if err := ioutil.WriteFile(image, make([]uint8, 0x4001), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{args: nil},
+		{args: []string{"--banks", "0x8000-0xBFFF:4", "--bank-select", "0xC000"}},
+		{args: []string{"--banks", "0x8000-0xBFFF:4", "--bank-select", "0xC000", "--bank-image", image}},
+		{args: []string{"--bank-select", "0xC000"}, err: "--bank-select and --bank-image need --banks"},
+		{args: []string{"--bank-image", image}, err: "--bank-select and --bank-image need --banks"},
+		{args: []string{"--banks", "0x8000-0xBFFF:4"}, err: "--banks needs a --bank-select address"},
+		{args: []string{"--banks", "0x8000-0xBFFF:4", "--bank-select", "port"}, err: `invalid bank select address "port"`},
+		{args: []string{"--banks", "0x8000-0xBFFF:4", "--bank-select", "0x8000"}, err: "bank select address 0x8000 is inside the bank window"},
+		{args: []string{"--banks", "0x8000-0xBFFF:-1", "--bank-select", "0xC000"}, err: "bank count -1 is not between"},
+		{args: []string{"--banks", "0x8000-0xBFFF:4", "--bank-select", "0xC000", "--bank-image", filepath.Join(dir, "missing.bin")}, err: "error reading bank image"},
+		{args: []string{"--banks", "0x8000-0x8FFF:4", "--bank-select", "0xC000", "--bank-image", image}, err: "image of 16385 bytes exceeds 4 banks of 4096 bytes"},
+	}
+
+	for _, test := range tests {
+		opts := newRunOptions("gebvm")
+		if err := opts.flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		m, err := newMemory(opts)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v for %q, want %q", err, test.args, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("got error %v for %q, want none", err, test.args)
+			continue
+		}
+		// Every address outside the window reads back what was written
+		m.Write(0x1234, 0x2A)
+		if m.Read(0x1234) != 0x2A {
+			t.Errorf("got 0x%X at 0x1234 for %q, want 0x2A", m.Read(0x1234), test.args)
+		}
+	}
+}
+
+func TestNewMemorySwitchesBanks(t *testing.T) {
+	opts := newRunOptions("gebvm")
+	if err := opts.flags.Parse([]string{"--banks", "0x8000-0xBFFF:2", "--bank-select", "0xC000"}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := newMemory(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Write(0x8000, 0x11)
+	m.Write(0xC000, 1)
+	m.Write(0x8000, 0x22)
+	if m.Read(0x8000) != 0x22 {
+		t.Errorf("got 0x%X in bank 1, want 0x22", m.Read(0x8000))
+	}
+	m.Write(0xC000, 0)
+	if m.Read(0x8000) != 0x11 {
+		t.Errorf("got 0x%X in bank 0, want 0x11", m.Read(0x8000))
+	}
+}
+
+func TestBanksRefuseSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "program.bin")
+	if err := ioutil.WriteFile(program, []uint8{processor.Halt}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(dir, "program.snap")
+	banks := []string{"--banks", "0x8000-0xBFFF:4", "--bank-select", "0xC000"}
+
+	if status := runCommand(append(banks, "--snapshot", snapshot, program)); status != 1 {
+		t.Errorf("got status %d running with banks and --snapshot, want 1", status)
+	}
+	if status := runCommand([]string{"--snapshot", snapshot, program}); status != 0 {
+		t.Fatalf("got status %d writing a snapshot without banks, want 0", status)
+	}
+	if status := resumeCommand(append(banks, snapshot)); status != 1 {
+		t.Errorf("got status %d resuming with banks, want 1", status)
+	}
+}
@@ -0,0 +1,130 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+)
+
+const maxBanks = 0xFF + 1 // Limit for a one byte bank number
+
+// Banked is a window onto one of several banks of RAM or ROM of the same
+// size, so programs can reach more memory than fits in the address
+// space.  Programs select a bank by writing its number to the device
+// returned by SelectPort.
+type Banked struct {
+	banks      [][]uint8
+	readOnly   []bool
+	selected   uint8
+	generation uint64
+}
+
+// NewBanked returns count banks of size bytes with bank 0 selected.  All
+// banks are RAM until SetReadOnly is called.
+func NewBanked(size, count int) (*Banked, error) {
+	if size < 1 || size > 0xFFFF+1 {
+		return nil, fmt.Errorf("bank size %d is not between 1 and %d", size, 0xFFFF+1)
+	}
+	if count < 1 || count > maxBanks {
+		return nil, fmt.Errorf("bank count %d is not between 1 and %d", count, maxBanks)
+	}
+	b := &Banked{banks: make([][]uint8, count), readOnly: make([]bool, count)}
+	for i := range b.banks {
+		b.banks[i] = make([]uint8, size)
+	}
+	return b, nil
+}
+
+// SetReadOnly makes a bank ignore writes, like ROM.  It can still be
+// loaded.
+func (b *Banked) SetReadOnly(bank int, readOnly bool) {
+	b.readOnly[bank] = readOnly
+}
+
+func (b *Banked) Read(offset uint16) uint8 {
+	return b.banks[b.selected][offset]
+}
+
+func (b *Banked) Write(offset uint16, value uint8) {
+	if !b.readOnly[b.selected] {
+		b.banks[b.selected][offset] = value
+	}
+}
+
+// Load writes to the selected bank, even if it is read-only.
+func (b *Banked) Load(offset uint16, value uint8) {
+	b.banks[b.selected][offset] = value
+	b.generation++
+}
+
+func (b *Banked) Size() int {
+	return len(b.banks[0])
+}
+
+// Count returns the number of banks.
+func (b *Banked) Count() int {
+	return len(b.banks)
+}
+
+// Selected returns the number of the selected bank.
+func (b *Banked) Selected() uint8 {
+	return b.selected
+}
+
+// Select selects a bank.  Bank numbers wrap around the number of banks.
+func (b *Banked) Select(bank uint8) {
+	bank = uint8(int(bank) % len(b.banks))
+	if bank != b.selected {
+		b.selected = bank
+		b.generation++
+	}
+}
+
+// SelectPort returns a device that selects a bank when written and reads
+// the selected bank number.  It is usually mapped to a single address.
+func (b *Banked) SelectPort() Device {
+	return &Peripheral{
+		ReadFunc:  func(offset uint16) uint8 { return b.selected },
+		WriteFunc: func(offset uint16, value uint8) { b.Select(value) },
+	}
+}
+
+// Generation changes whenever the selected bank changes or the selected
+// bank is loaded, so the Processor discards instructions it decoded from
+// the previous contents of the window.
+func (b *Banked) Generation() uint64 {
+	return b.generation
+}
+
+// LoadBank copies data into a bank starting at offset.
+func (b *Banked) LoadBank(bank int, offset int, data []uint8) error {
+	if bank < 0 || bank >= len(b.banks) {
+		return fmt.Errorf("bank %d does not exist", bank)
+	}
+	if offset < 0 || offset+len(data) > b.Size() {
+		return errors.New("data length exceeds bank size")
+	}
+	copy(b.banks[bank][offset:], data)
+	if bank == int(b.selected) {
+		b.generation++
+	}
+	return nil
+}
+
+// LoadImage copies an image larger than one bank across the banks in
+// order, starting at the beginning of bank 0.
+func (b *Banked) LoadImage(image []uint8) error {
+	if len(image) > b.Size()*len(b.banks) {
+		return fmt.Errorf("image of %d bytes exceeds %d banks of %d bytes", len(image), len(b.banks), b.Size())
+	}
+	for bank := 0; len(image) > 0; bank++ {
+		n := b.Size()
+		if n > len(image) {
+			n = len(image)
+		}
+		if err := b.LoadBank(bank, 0, image[:n]); err != nil {
+			return err
+		}
+		image = image[n:]
+	}
+	return nil
+}
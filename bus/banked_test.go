@@ -0,0 +1,117 @@
+package bus_test
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/bus"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestBankedSelect(t *testing.T) {
+	banked, err := bus.NewBanked(0x10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	banked.Write(0x0001, 0xAA)
+	banked.Select(1)
+	banked.Write(0x0001, 0xBB)
+	if banked.Read(0x0001) != 0xBB {
+		t.Errorf("got 0x%X in bank 1, want 0xBB", banked.Read(0x0001))
+	}
+	banked.Select(3) // Wraps to bank 0
+	if banked.Selected() != 0 || banked.Read(0x0001) != 0xAA {
+		t.Errorf("got 0x%X in bank %d, want 0xAA in bank 0", banked.Read(0x0001), banked.Selected())
+	}
+
+	generation := banked.Generation()
+	port := banked.SelectPort()
+	port.Write(0x0000, 2)
+	if port.Read(0x0000) != 2 || banked.Generation() == generation {
+		t.Errorf("got bank %d and generation %d, want bank 2 and a new generation",
+			port.Read(0x0000), banked.Generation())
+	}
+}
+
+func TestBankedReadOnly(t *testing.T) {
+	banked, _ := bus.NewBanked(0x10, 2)
+	banked.SetReadOnly(1, true)
+	banked.Select(1)
+	banked.Load(0x0000, 0x42)
+	banked.Write(0x0000, 0x24)
+	if banked.Read(0x0000) != 0x42 {
+		t.Errorf("got 0x%X after writing read-only bank, want 0x42", banked.Read(0x0000))
+	}
+}
+
+func TestBankedLoadImage(t *testing.T) {
+	banked, _ := bus.NewBanked(0x10, 3)
+	image := make([]uint8, 0x25)
+	for i := range image {
+		image[i] = uint8(i)
+	}
+	if err := banked.LoadImage(image); err != nil {
+		t.Fatal(err)
+	}
+	banked.Select(2)
+	if banked.Read(0x0004) != 0x24 {
+		t.Errorf("got 0x%X at bank 2 offset 4, want 0x24", banked.Read(0x0004))
+	}
+	if err := banked.LoadImage(make([]uint8, 0x31)); err == nil {
+		t.Error("got nil, want error for image larger than the banks")
+	}
+	if err := banked.LoadBank(3, 0, []uint8{0x01}); err == nil {
+		t.Error("got nil, want error for bank that does not exist")
+	}
+}
+
+func TestNewBankedErrors(t *testing.T) {
+	tests := []struct {
+		size  int
+		count int
+	}{
+		{size: 0, count: 1},
+		{size: 0x10001, count: 1},
+		{size: 0x10, count: 0},
+		{size: 0x10, count: 257},
+	}
+	for _, test := range tests {
+		if _, err := bus.NewBanked(test.size, test.count); err == nil {
+			t.Errorf("got nil, want error for %d banks of %d bytes", test.count, test.size)
+		}
+	}
+}
+
+func TestProcessorWithBankedMemory(t *testing.T) {
+	banked, _ := bus.NewBanked(0x100, 2)
+	banked.LoadBank(0, 0, []uint8{processor.MoveLitReg, 0x01, 0x01, processor.Return})
+	banked.LoadBank(1, 0, []uint8{processor.MoveLitReg, 0x02, 0x01, processor.Return})
+	b, err := bus.New(
+		bus.Mapping{Start: 0x0000, End: 0x0FFF, Device: bus.NewRAM(0x1000)},
+		bus.Mapping{Start: 0x1000, End: 0x10FF, Device: banked},
+		bus.Mapping{Start: 0x2000, End: 0x2000, Device: banked.SelectPort()},
+		bus.Mapping{Start: 0xFF00, End: 0xFFFF, Device: bus.NewRAM(0x100)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Calls the same address before and after selecting bank 1
+	b.LoadProgram([]uint8{
+		processor.Call, 0x10, 0x00,
+		processor.MoveRegReg, 0x01, 0x02,
+		processor.MoveLitReg, 0x20, 0x04,
+		processor.MoveLitReg, 0x00, 0x05,
+		processor.MoveLitMem, 0x01, 0x04,
+		processor.Call, 0x10, 0x00,
+		processor.Halt,
+	})
+	p := processor.New(b, bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout), bufio.NewWriter(os.Stderr))
+	if status := p.Run(); status != 0 {
+		t.Fatalf("got status %d, want 0", status)
+	}
+	if p.RegisterValue(0x02) != 0x01 || p.RegisterValue(0x01) != 0x02 {
+		t.Errorf("got R2 0x%X and R1 0x%X, want 0x01 from bank 0 and 0x02 from bank 1",
+			p.RegisterValue(0x02), p.RegisterValue(0x01))
+	}
+}
@@ -0,0 +1,147 @@
+// Package bus provides a MemoryDevice that routes address ranges to
+// memory mapped devices such as RAM, ROM and peripherals.
+package bus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+)
+
+// Device is a memory mapped device.  Offsets are relative to the start
+// of the address range the device is mapped to.
+type Device interface {
+	Read(offset uint16) uint8
+	Write(offset uint16, value uint8)
+}
+
+// Loader is implemented by devices that ignore writes during execution
+// but can still be loaded with a program, such as ROM.
+type Loader interface {
+	Load(offset uint16, value uint8)
+}
+
+// Sizer is implemented by devices with a fixed size.  A device that
+// implements Sizer cannot be mapped to a range larger than its size.
+type Sizer interface {
+	Size() int
+}
+
+// Mapping maps the addresses from Start to End, inclusive, to a Device.
+type Mapping struct {
+	Start  uint16
+	End    uint16
+	Device Device
+}
+
+const maxMappings = 255
+
+// Generationer is implemented by devices whose contents can change
+// without a write through the Bus, such as Banked.  Generation must
+// return a new value after any such change.
+type Generationer interface {
+	Generation() uint64
+}
+
+// Bus routes reads and writes to mapped devices.  Reads from unmapped
+// addresses return 0x00 and writes to them are ignored.
+type Bus struct {
+	mappings []Mapping
+	routes   [memory.MemorySize]uint8 // index into mappings + 1, or 0 if unmapped
+	volatile []Generationer
+}
+
+// New returns a Bus with the given device mappings.  Mappings cannot overlap.
+func New(mappings ...Mapping) (*Bus, error) {
+	if len(mappings) > maxMappings {
+		return nil, fmt.Errorf("bus supports at most %d mappings", maxMappings)
+	}
+	b := &Bus{mappings: mappings}
+	for i, m := range mappings {
+		if m.Device == nil {
+			return nil, fmt.Errorf("mapping 0x%04X-0x%04X has no device", m.Start, m.End)
+		}
+		if m.Start > m.End {
+			return nil, fmt.Errorf("mapping 0x%04X-0x%04X ends before it starts", m.Start, m.End)
+		}
+		if sizer, ok := m.Device.(Sizer); ok && int(m.End-m.Start)+1 > sizer.Size() {
+			return nil, fmt.Errorf("mapping 0x%04X-0x%04X is larger than its %d byte device",
+				m.Start, m.End, sizer.Size())
+		}
+		for address := int(m.Start); address <= int(m.End); address++ {
+			if b.routes[address] != 0 {
+				other := mappings[b.routes[address]-1]
+				return nil, fmt.Errorf("mapping 0x%04X-0x%04X overlaps mapping 0x%04X-0x%04X",
+					m.Start, m.End, other.Start, other.End)
+			}
+			b.routes[address] = uint8(i + 1)
+		}
+		if g, ok := m.Device.(Generationer); ok {
+			b.volatile = append(b.volatile, g)
+		}
+	}
+	return b, nil
+}
+
+// Generation combines the generations of the mapped devices that
+// implement Generationer, so a Bus is a processor.VolatileMemoryDevice.
+func (b *Bus) Generation() uint64 {
+	var generation uint64
+	for _, g := range b.volatile {
+		generation += g.Generation()
+	}
+	return generation
+}
+
+// route returns the mapping for an address, or nil if it is unmapped.
+func (b *Bus) route(address uint16) *Mapping {
+	index := b.routes[address]
+	if index == 0 {
+		return nil
+	}
+	return &b.mappings[index-1]
+}
+
+func (b *Bus) Read(address uint16) uint8 {
+	m := b.route(address)
+	if m == nil {
+		return 0x00
+	}
+	return m.Device.Read(address - m.Start)
+}
+
+func (b *Bus) Write(address uint16, value uint8) {
+	m := b.route(address)
+	if m == nil {
+		return
+	}
+	m.Device.Write(address-m.Start, value)
+}
+
+// LoadProgram writes program to the devices mapped from address 0x0000.
+// Devices that implement Loader are loaded instead of written.
+func (b *Bus) LoadProgram(program []uint8) error {
+	return b.LoadAt(0x0000, program)
+}
+
+// LoadAt writes data to the devices mapped from address.  Devices that
+// implement Loader are loaded instead of written.
+func (b *Bus) LoadAt(address uint16, data []uint8) error {
+	if int(address)+len(data) > memory.MemorySize {
+		return errors.New("program length exceeds available memory")
+	}
+	for i, value := range data {
+		target := address + uint16(i)
+		m := b.route(target)
+		if m == nil {
+			return fmt.Errorf("program address 0x%04X is not mapped to a device", target)
+		}
+		if loader, ok := m.Device.(Loader); ok {
+			loader.Load(target-m.Start, value)
+		} else {
+			m.Device.Write(target-m.Start, value)
+		}
+	}
+	return nil
+}
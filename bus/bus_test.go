@@ -0,0 +1,100 @@
+package bus_test
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/bus"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestReadAndWriteRouting(t *testing.T) {
+	ram := bus.NewRAM(0x100)
+	rom := bus.NewROM(0x100)
+	b, err := bus.New(
+		bus.Mapping{Start: 0x0000, End: 0x00FF, Device: rom},
+		bus.Mapping{Start: 0x1000, End: 0x10FF, Device: ram},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Write(0x1042, 0xAB)
+	if b.Read(0x1042) != 0xAB || ram.Read(0x0042) != 0xAB {
+		t.Errorf("got 0x%X at 0x1042, want 0xAB", b.Read(0x1042))
+	}
+	b.Write(0x0042, 0xAB)
+	if b.Read(0x0042) != 0x00 {
+		t.Errorf("got 0x%X at 0x0042 after writing ROM, want 0x00", b.Read(0x0042))
+	}
+	b.Write(0x2000, 0xAB)
+	if b.Read(0x2000) != 0x00 {
+		t.Errorf("got 0x%X at unmapped 0x2000, want 0x00", b.Read(0x2000))
+	}
+}
+
+func TestLoadProgram(t *testing.T) {
+	rom := bus.NewROM(0x10)
+	b, _ := bus.New(bus.Mapping{Start: 0x0000, End: 0x000F, Device: rom})
+	if err := b.LoadProgram([]uint8{0x01, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	if b.Read(0x0001) != 0x02 {
+		t.Errorf("got 0x%X at 0x0001, want 0x02", b.Read(0x0001))
+	}
+	if err := b.LoadProgram(make([]uint8, 0x11)); err == nil {
+		t.Error("got nil, want error for program beyond mapped devices")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := [][]bus.Mapping{
+		{{Start: 0x0010, End: 0x000F, Device: bus.NewRAM(0x10)}},
+		{{Start: 0x0000, End: 0x000F}},
+		{{Start: 0x0000, End: 0x00FF, Device: bus.NewRAM(0x10)}},
+		{
+			{Start: 0x0000, End: 0x00FF, Device: bus.NewRAM(0x100)},
+			{Start: 0x00FF, End: 0x01FF, Device: bus.NewRAM(0x100)},
+		},
+	}
+	for _, mappings := range tests {
+		if _, err := bus.New(mappings...); err == nil {
+			t.Errorf("got nil, want error for %+v", mappings)
+		}
+	}
+}
+
+func TestProcessorWithPeripheral(t *testing.T) {
+	var output []uint8
+	console := &bus.Peripheral{
+		ReadFunc:  func(offset uint16) uint8 { return 0x2A },
+		WriteFunc: func(offset uint16, value uint8) { output = append(output, value) },
+	}
+	b, err := bus.New(
+		bus.Mapping{Start: 0x0000, End: 0x7FFF, Device: bus.NewROM(0x8000)},
+		bus.Mapping{Start: 0x8000, End: 0x8000, Device: console},
+		bus.Mapping{Start: 0xFF00, End: 0xFFFF, Device: bus.NewRAM(0x100)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.LoadProgram([]uint8{
+		processor.MoveLitReg, 0x80, 0x02,
+		processor.MoveMemReg, 0x02, 0x01, // Read from console
+		processor.Inc, 0x01,
+		processor.MoveRegMem, 0x01, 0x02, // Write to console
+		processor.StackPushReg, 0x01,
+		processor.Halt,
+	})
+	p := processor.New(b, bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout), bufio.NewWriter(os.Stderr))
+	if status := p.Run(); status != 0 {
+		t.Fatalf("got status %d, want 0", status)
+	}
+	if len(output) != 1 || output[0] != 0x2B {
+		t.Errorf("got % X written to console, want 2B", output)
+	}
+	if b.Read(processor.StackStart) != 0x2B {
+		t.Errorf("got 0x%X at stack start, want 0x2B", b.Read(processor.StackStart))
+	}
+}
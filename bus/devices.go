@@ -0,0 +1,68 @@
+package bus
+
+// RAM is readable and writable memory.
+type RAM struct {
+	data []uint8
+}
+
+func NewRAM(size int) *RAM {
+	return &RAM{data: make([]uint8, size)}
+}
+
+func (r *RAM) Read(offset uint16) uint8 {
+	return r.data[offset]
+}
+
+func (r *RAM) Write(offset uint16, value uint8) {
+	r.data[offset] = value
+}
+
+func (r *RAM) Size() int {
+	return len(r.data)
+}
+
+// ROM is read-only memory.  Writes are ignored, but a program can be
+// loaded into it.
+type ROM struct {
+	data []uint8
+}
+
+func NewROM(size int) *ROM {
+	return &ROM{data: make([]uint8, size)}
+}
+
+func (r *ROM) Read(offset uint16) uint8 {
+	return r.data[offset]
+}
+
+func (r *ROM) Write(offset uint16, value uint8) {}
+
+func (r *ROM) Load(offset uint16, value uint8) {
+	r.data[offset] = value
+}
+
+func (r *ROM) Size() int {
+	return len(r.data)
+}
+
+// Peripheral adapts functions to a Device, so peripherals such as
+// consoles and timers can be mapped without declaring a new type.  Reads
+// return 0x00 when ReadFunc is nil and writes are ignored when WriteFunc
+// is nil.
+type Peripheral struct {
+	ReadFunc  func(offset uint16) uint8
+	WriteFunc func(offset uint16, value uint8)
+}
+
+func (p *Peripheral) Read(offset uint16) uint8 {
+	if p.ReadFunc == nil {
+		return 0x00
+	}
+	return p.ReadFunc(offset)
+}
+
+func (p *Peripheral) Write(offset uint16, value uint8) {
+	if p.WriteFunc != nil {
+		p.WriteFunc(offset, value)
+	}
+}
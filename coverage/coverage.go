@@ -0,0 +1,222 @@
+// Package coverage records which instructions and branches a program
+// executes, and writes LCOV and HTML reports.
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/scottmcleodjr/gebvm/disassembler"
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/symbols"
+)
+
+// Branch counts the outcomes of a conditional jump.
+type Branch struct {
+	Taken   uint64 // Times execution continued at the target
+	Skipped uint64 // Times execution continued at the next instruction
+}
+
+// Coverage is a processor.Tracer that counts executed instructions and
+// branch outcomes.
+type Coverage struct {
+	hits     [0xFFFF + 1]uint64
+	branches map[uint16]*Branch
+}
+
+func New() *Coverage {
+	return &Coverage{branches: map[uint16]*Branch{}}
+}
+
+// Trace counts a record.
+func (c *Coverage) Trace(record *processor.TraceRecord) {
+	c.hits[record.InstructionPointer]++
+	info, found := processor.LookupOpcode(record.Opcode)
+	if !found || info.Flow != processor.FlowBranch || len(record.Operands)+1 != int(info.Size()) {
+		return
+	}
+
+	b, found := c.branches[record.InstructionPointer]
+	if !found {
+		b = &Branch{}
+		c.branches[record.InstructionPointer] = b
+	}
+	offset := 0
+	for _, kind := range info.Operands[:info.Target()] {
+		offset += int(kind.Size())
+	}
+	target := uint16(record.Operands[offset])<<8 + uint16(record.Operands[offset+1])
+	if record.NextInstructionPointer == target {
+		b.Taken++
+	} else {
+		b.Skipped++
+	}
+}
+
+// Hits returns the number of times the instruction at address executed.
+func (c *Coverage) Hits(address uint16) uint64 {
+	return c.hits[address]
+}
+
+// Branch returns the outcomes of the conditional jump at address, or
+// false if it never executed.
+func (c *Coverage) Branch(address uint16) (Branch, bool) {
+	b, found := c.branches[address]
+	if !found {
+		return Branch{}, false
+	}
+	return *b, true
+}
+
+// Line is a line of the annotated listing.
+type Line struct {
+	disassembler.Line
+	Hits   uint64
+	Branch *Branch      // Outcomes for conditional jumps, or nil
+	Source symbols.Line // Zero if there are no symbols for the line
+}
+
+// IsBranch reports whether the line is a conditional jump.
+func (l Line) IsBranch() bool {
+	return l.Instruction != nil && l.Instruction.Flow == processor.FlowBranch
+}
+
+// Listing returns the disassembly of segments annotated with the
+// coverage.  Executed addresses, the entry address, and the instructions
+// in the symbols are decoded as instructions.  table can be nil.
+func (c *Coverage) Listing(segments []executable.Segment, entry uint16, table *symbols.Table) []Line {
+	var lines []Line
+	for _, segment := range segments {
+		entries := []uint16{entry}
+		for i := range segment.Data {
+			address := segment.Address + uint16(i)
+			if c.hits[address] > 0 {
+				entries = append(entries, address)
+			}
+		}
+		if table != nil {
+			for _, line := range table.Lines {
+				if !line.Data {
+					entries = append(entries, line.Address)
+				}
+			}
+		}
+
+		for _, l := range disassembler.Recursive(segment.Data, segment.Address, entries...) {
+			line := Line{Line: l, Hits: c.hits[l.Address]}
+			if l.Instruction == nil {
+				line.Hits = 0
+			}
+			if line.IsBranch() {
+				line.Branch = &Branch{}
+				if b, found := c.branches[l.Address]; found {
+					*line.Branch = *b
+				}
+			}
+			if table != nil {
+				line.Source, _ = table.Location(l.Address)
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Summary holds coverage totals.
+type Summary struct {
+	Instructions         int // Instructions in the listing
+	ExecutedInstructions int
+	Branches             int // Possible branch outcomes, two per conditional jump
+	ExecutedBranches     int
+}
+
+// Summarize returns the totals for a listing.
+func Summarize(lines []Line) Summary {
+	var s Summary
+	for _, line := range lines {
+		if line.Instruction == nil {
+			continue
+		}
+		s.Instructions++
+		if line.Hits > 0 {
+			s.ExecutedInstructions++
+		}
+		if line.Branch != nil {
+			s.Branches += 2
+			if line.Branch.Taken > 0 {
+				s.ExecutedBranches++
+			}
+			if line.Branch.Skipped > 0 {
+				s.ExecutedBranches++
+			}
+		}
+	}
+	return s
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(count) / float64(total)
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("instructions: %d of %d executed (%.1f%%), branches: %d of %d outcomes (%.1f%%)",
+		s.ExecutedInstructions, s.Instructions, percent(s.ExecutedInstructions, s.Instructions),
+		s.ExecutedBranches, s.Branches, percent(s.ExecutedBranches, s.Branches))
+}
+
+// WriteLCOV writes a listing as an LCOV tracefile.  Lines are reported
+// against their source files, so lines without symbols are left out.
+// Each branch instruction is block 0 of its line, with the taken and
+// not taken outcomes as branches 0 and 1.
+func WriteLCOV(w io.Writer, lines []Line) error {
+	files := map[string][]Line{}
+	var names []string
+	for _, line := range lines {
+		if line.Instruction == nil || line.Source.File == "" {
+			continue
+		}
+		if _, found := files[line.Source.File]; !found {
+			names = append(names, line.Source.File)
+		}
+		files[line.Source.File] = append(files[line.Source.File], line)
+	}
+	sort.Strings(names)
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	for _, name := range names {
+		printf("TN:\nSF:%s\n", name)
+		for _, line := range files[name] {
+			if line.Branch != nil {
+				printf("BRDA:%d,0,0,%s\n", line.Source.Line, branchCount(line.Hits, line.Branch.Taken))
+				printf("BRDA:%d,0,1,%s\n", line.Source.Line, branchCount(line.Hits, line.Branch.Skipped))
+			}
+		}
+		for _, line := range files[name] {
+			printf("DA:%d,%d\n", line.Source.Line, line.Hits)
+		}
+		s := Summarize(files[name])
+		printf("BRF:%d\nBRH:%d\n", s.Branches, s.ExecutedBranches)
+		printf("LF:%d\nLH:%d\n", s.Instructions, s.ExecutedInstructions)
+		printf("end_of_record\n")
+	}
+	return err
+}
+
+// branchCount returns an LCOV branch count, which is - for branches that
+// never executed.
+func branchCount(hits, count uint64) string {
+	if hits == 0 {
+		return "-"
+	}
+	return fmt.Sprint(count)
+}
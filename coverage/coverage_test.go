@@ -0,0 +1,138 @@
+package coverage_test
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/assembler"
+	"github.com/scottmcleodjr/gebvm/coverage"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+const testSource = `
+start:  MLR 2, R2
+again:  DEC R2
+        JNE R2, again
+        JEQ R2, done
+unused: NOP
+done:   HLT
+text:   .string "This text is long enough to be collapsed in the listing"
+`
+
+func runCovered(t *testing.T) (*coverage.Coverage, *assembler.Program) {
+	t.Helper()
+	program, err := assembler.Assemble("test.asm", []byte(testSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := memory.New()
+	m.LoadProgram(program.Image)
+	c := coverage.New()
+	p := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+		processor.WithTracer(c),
+	)
+	if status := p.Run(); status != 0 {
+		t.Fatalf("got status %d, want 0", status)
+	}
+	return c, program
+}
+
+func TestHitsAndBranches(t *testing.T) {
+	c, _ := runCovered(t)
+	hits := map[uint16]uint64{0x0000: 1, 0x0003: 2, 0x0005: 2, 0x0009: 1, 0x000D: 0, 0x000E: 1}
+	for address, expected := range hits {
+		if got := c.Hits(address); got != expected {
+			t.Errorf("got %d hits at 0x%04X, want %d", got, address, expected)
+		}
+	}
+
+	branches := map[uint16]coverage.Branch{0x0005: {Taken: 1, Skipped: 1}, 0x0009: {Taken: 1}}
+	for address, expected := range branches {
+		got, found := c.Branch(address)
+		if !found || got != expected {
+			t.Errorf("got %+v, %t at 0x%04X, want %+v", got, found, address, expected)
+		}
+	}
+	if _, found := c.Branch(0x0003); found {
+		t.Errorf("got a branch at 0x0003, want none")
+	}
+}
+
+func TestListing(t *testing.T) {
+	c, program := runCovered(t)
+	lines := c.Listing(program.Segments, program.Entry, program.Symbols)
+
+	var unused *coverage.Line
+	for i := range lines {
+		if lines[i].Address == 0x000D {
+			unused = &lines[i]
+		}
+	}
+	if unused == nil || unused.Instruction == nil || unused.Instruction.Mnemonic != "NOP" {
+		t.Fatalf("got %+v at 0x000D, want an unexecuted NOP", unused)
+	}
+	if unused.Hits != 0 || unused.Source.Line != 6 {
+		t.Errorf("got %d hits on line %d, want 0 hits on line 6", unused.Hits, unused.Source.Line)
+	}
+
+	expected := coverage.Summary{Instructions: 6, ExecutedInstructions: 5, Branches: 4, ExecutedBranches: 3}
+	if s := coverage.Summarize(lines); s != expected {
+		t.Errorf("got %+v, want %+v", s, expected)
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	c, program := runCovered(t)
+	var out bytes.Buffer
+	if err := coverage.WriteLCOV(&out, c.Listing(program.Segments, program.Entry, program.Symbols)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `TN:
+SF:test.asm
+BRDA:4,0,0,1
+BRDA:4,0,1,1
+BRDA:5,0,0,1
+BRDA:5,0,1,0
+DA:2,1
+DA:3,2
+DA:4,2
+DA:5,1
+DA:6,0
+DA:7,1
+BRF:4
+BRH:3
+LF:6
+LH:5
+end_of_record
+`
+	if out.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	c, program := runCovered(t)
+	var out bytes.Buffer
+	if err := coverage.WriteHTML(&out, "test.asm", c.Listing(program.Segments, program.Entry, program.Symbols)); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, expected := range []string{
+		`<tr class="partial"><td class="count">1</td><td>taken 1, skipped 0</td><td>0009</td>`,
+		`<tr class="miss"><td class="count">0</td><td></td><td>000D</td><td>00</td><td>NOP</td><td>test.asm:6</td>`,
+		"instructions: 5 of 6 executed (83.3%), branches: 3 of 4 outcomes (75.0%)",
+		"more data lines",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("got\n%s\nwant it to contain %q", html, expected)
+		}
+	}
+}
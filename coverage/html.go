@@ -0,0 +1,129 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// collapseDataLines is the number of consecutive data lines shown before
+// the rest of the run is collapsed into one row.
+const collapseDataLines = 4
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} coverage</title>
+<style>
+body { font-family: monospace; }
+table { border-collapse: collapse; }
+td { padding: 0 0.75em; white-space: pre; }
+td.count { text-align: right; }
+tr.hit { background: #dfd; }
+tr.miss { background: #fdd; }
+tr.partial { background: #ffd; }
+tr.data { color: #888; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Summary}}</p>
+<table>
+<tr><th>Hits</th><th>Branches</th><th>Address</th><th>Bytes</th><th>Instruction</th><th>Source</th><th></th></tr>
+{{range .Rows}}<tr class="{{.Class}}"><td class="count">{{.Hits}}</td><td>{{.Branches}}</td><td>{{.Address}}</td><td>{{.Bytes}}</td><td>{{.Text}}</td><td>{{.Location}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type htmlRow struct {
+	Class    string
+	Hits     string
+	Branches string
+	Address  string
+	Bytes    string
+	Text     string
+	Location string
+	Source   string
+}
+
+// WriteHTML writes a listing as an HTML page.  Each instruction shows its
+// hit count, branch outcomes, and its source line when the source file
+// can be read.  Long runs of data are collapsed.
+func WriteHTML(w io.Writer, title string, lines []Line) error {
+	sources := map[string][]string{}
+	sourceLine := func(file string, line int) string {
+		text, found := sources[file]
+		if !found {
+			data, err := ioutil.ReadFile(file)
+			if err == nil {
+				text = strings.Split(string(data), "\n")
+			}
+			sources[file] = text
+		}
+		if line < 1 || line > len(text) {
+			return ""
+		}
+		return strings.TrimSpace(text[line-1])
+	}
+
+	var rows []htmlRow
+	dataRun := 0
+	for i, line := range lines {
+		if line.Instruction != nil {
+			dataRun = 0
+		} else {
+			dataRun++
+			if dataRun > collapseDataLines {
+				if dataRun == collapseDataLines+1 {
+					rows = append(rows, htmlRow{Class: "data", Text: fmt.Sprintf("... %d more data lines", dataLineCount(lines[i:]))})
+				}
+				continue
+			}
+		}
+
+		row := htmlRow{
+			Class:   "data",
+			Address: fmt.Sprintf("%04X", line.Address),
+			Bytes:   fmt.Sprintf("% X", line.Bytes),
+			Text:    line.Text(),
+		}
+		if line.Instruction != nil {
+			row.Hits = fmt.Sprint(line.Hits)
+			row.Class = "hit"
+			if line.Hits == 0 {
+				row.Class = "miss"
+			}
+		}
+		if line.Branch != nil {
+			row.Branches = fmt.Sprintf("taken %d, skipped %d", line.Branch.Taken, line.Branch.Skipped)
+			if line.Hits > 0 && (line.Branch.Taken == 0 || line.Branch.Skipped == 0) {
+				row.Class = "partial"
+			}
+		}
+		if line.Source.File != "" {
+			row.Location = fmt.Sprintf("%s:%d", line.Source.File, line.Source.Line)
+			row.Source = sourceLine(line.Source.File, line.Source.Line)
+		}
+		rows = append(rows, row)
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		Title   string
+		Summary Summary
+		Rows    []htmlRow
+	}{title, Summarize(lines), rows})
+}
+
+// dataLineCount returns the number of data lines at the start of lines.
+func dataLineCount(lines []Line) int {
+	for i, line := range lines {
+		if line.Instruction != nil {
+			return i
+		}
+	}
+	return len(lines)
+}
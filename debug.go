@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/scottmcleodjr/gebvm/debugger"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+const (
+	debugHelpText string = `Provide the bytecode file to debug as an argument.
+
+  Example: ./gebvm debug hello_world.geb
+
+`
+)
+
+// debugCommand runs a bytecode file under the interactive debugger.
+func debugCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, debugHelpText)
+		return 1
+	}
+
+	m, e, err := loadProgram(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	table, err := loadSymbols(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	var options []processor.Option
+	if table != nil {
+		options = append(options, processor.WithSymbols(table))
+	}
+
+	// Commands and RIN input share stdin
+	reader := bufio.NewReader(os.Stdin)
+	proc := processor.New(
+		m,
+		reader,
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+		options...,
+	)
+	proc.SetInstructionPointer(e.Entry)
+	return debugger.New(proc, m, reader, os.Stdout).Run()
+}
@@ -0,0 +1,273 @@
+// Package debugger provides an interactive command line debugger that
+// runs a Processor one instruction at a time.
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/scottmcleodjr/gebvm/disassembler"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+const helpText string = `Commands:
+  s, step [count]            Execute count instructions (default 1)
+  c, continue                Execute until a breakpoint, halt or error
+  b, break <address>         Set a breakpoint
+  d, delete <address>        Clear a breakpoint
+  l, list                    List breakpoints
+  r, regs                    Print registers, IP, SP, stack size and flags
+  x, mem <address> [length]  Hexdump length bytes of memory (default 16)
+  set <Rn|address> <value>   Set a register or memory byte
+  h, help                    Print this help
+  q, quit                    Exit the debugger
+`
+
+// Debugger controls the execution of a Processor.
+type Debugger struct {
+	proc        *processor.Processor
+	memory      processor.MemoryDevice
+	reader      *bufio.Reader // reader for commands, shared with RIN
+	writer      io.Writer
+	breakpoints map[uint16]bool
+	stopped     bool // true after halt or error
+}
+
+// New returns a Debugger for p.  The memory must be the MemoryDevice used
+// by p.  Commands are read from r, which can be shared with the Processor.
+func New(p *processor.Processor, m processor.MemoryDevice, r *bufio.Reader, w io.Writer) *Debugger {
+	return &Debugger{
+		proc:        p,
+		memory:      m,
+		reader:      r,
+		writer:      w,
+		breakpoints: map[uint16]bool{},
+	}
+}
+
+// Run reads and executes commands until quit or the end of input.
+// It returns 1 if the program stopped with errors, 0 otherwise.
+func (d *Debugger) Run() int {
+	d.printInstruction()
+	for {
+		fmt.Fprint(d.writer, "(gebvm) ")
+		line, err := d.reader.ReadString('\n')
+		if strings.TrimSpace(line) != "" && !d.Execute(line) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(d.writer)
+			break
+		}
+	}
+	if len(d.proc.Errors()) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Execute runs a single command.  It returns false if the command was quit.
+func (d *Debugger) Execute(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return true
+	}
+	args := fields[1:]
+	switch fields[0] {
+	case "s", "step":
+		d.step(args)
+	case "c", "continue":
+		d.continueRunning()
+	case "b", "break":
+		if address, ok := d.parseArgs(args, 1, 1); ok {
+			d.breakpoints[address[0]] = true
+		}
+	case "d", "delete":
+		if address, ok := d.parseArgs(args, 1, 1); ok {
+			if !d.breakpoints[address[0]] {
+				fmt.Fprintf(d.writer, "no breakpoint at 0x%04X\n", address[0])
+			}
+			delete(d.breakpoints, address[0])
+		}
+	case "l", "list":
+		d.listBreakpoints()
+	case "r", "regs":
+		d.printRegisters()
+	case "x", "mem":
+		d.hexdump(args)
+	case "set":
+		d.set(args)
+	case "h", "help":
+		fmt.Fprint(d.writer, helpText)
+	case "q", "quit":
+		return false
+	default:
+		fmt.Fprintf(d.writer, "unknown command %q, try help\n", fields[0])
+	}
+	return true
+}
+
+// parseArgs parses between min and max numeric arguments.
+func (d *Debugger) parseArgs(args []string, min, max int) ([]uint16, bool) {
+	if len(args) < min || len(args) > max {
+		fmt.Fprintf(d.writer, "expected %d to %d arguments, found %d\n", min, max, len(args))
+		return nil, false
+	}
+	values := make([]uint16, len(args))
+	for i, arg := range args {
+		value, err := strconv.ParseUint(arg, 0, 16)
+		if err != nil {
+			fmt.Fprintf(d.writer, "invalid number %q\n", arg)
+			return nil, false
+		}
+		values[i] = uint16(value)
+	}
+	return values, true
+}
+
+// stepOnce executes a single instruction and reports when the program stops.
+func (d *Debugger) stepOnce() bool {
+	if d.stopped {
+		fmt.Fprintln(d.writer, "program is not running")
+		return false
+	}
+	if !d.proc.Step() {
+		d.stopped = true
+		errs := d.proc.Errors()
+		if len(errs) == 0 {
+			fmt.Fprintf(d.writer, "program halted at 0x%04X\n", d.proc.InstructionPointer())
+		}
+		for _, err := range errs {
+			fmt.Fprintf(d.writer, "error: %s\n", err)
+		}
+		return false
+	}
+	return true
+}
+
+func (d *Debugger) step(args []string) {
+	count := []uint16{1}
+	if len(args) > 0 {
+		var ok bool
+		if count, ok = d.parseArgs(args, 1, 1); !ok {
+			return
+		}
+	}
+	for i := uint16(0); i < count[0]; i++ {
+		if !d.stepOnce() {
+			return
+		}
+	}
+	d.printInstruction()
+}
+
+func (d *Debugger) continueRunning() {
+	for d.stepOnce() {
+		if d.breakpoints[d.proc.InstructionPointer()] {
+			fmt.Fprintf(d.writer, "breakpoint at 0x%04X\n", d.proc.InstructionPointer())
+			d.printInstruction()
+			return
+		}
+	}
+}
+
+func (d *Debugger) listBreakpoints() {
+	addresses := make([]int, 0, len(d.breakpoints))
+	for address := range d.breakpoints {
+		addresses = append(addresses, int(address))
+	}
+	sort.Ints(addresses)
+	for _, address := range addresses {
+		fmt.Fprintf(d.writer, "0x%04X\n", address)
+	}
+}
+
+// printInstruction prints the disassembly of the instruction at IP.
+func (d *Debugger) printInstruction() {
+	ip := d.proc.InstructionPointer()
+	size := uint16(1)
+	if info, found := processor.LookupOpcode(d.memory.Read(ip)); found {
+		size = info.Size()
+	}
+	bytes := make([]uint8, 0, size)
+	for i := uint16(0); i < size && uint32(ip)+uint32(i) <= 0xFFFF; i++ {
+		bytes = append(bytes, d.memory.Read(ip+i))
+	}
+	line := disassembler.Linear(bytes, ip)[0]
+	if line.Instruction == nil {
+		line.Bytes = line.Bytes[:1]
+	}
+	fmt.Fprintf(d.writer, "0x%04X: %s\n", ip, line.Text())
+}
+
+func (d *Debugger) printRegisters() {
+	registers := make([]string, processor.RegisterCount)
+	for r := range registers {
+		registers[r] = fmt.Sprintf("R%d=0x%02X", r, d.proc.RegisterValue(uint8(r)))
+	}
+	fmt.Fprintf(d.writer, "%s\nIP=0x%04X SP=0x%04X FP=0x%04X StackSize=%d Flags=%s\n", strings.Join(registers, " "),
+		d.proc.InstructionPointer(), d.proc.StackPointer(), d.proc.FramePointer(), d.proc.StackSize(),
+		processor.FlagString(d.proc.Flags()))
+}
+
+func (d *Debugger) hexdump(args []string) {
+	values, ok := d.parseArgs(args, 1, 2)
+	if !ok {
+		return
+	}
+	start := uint32(values[0])
+	end := start + 16
+	if len(values) == 2 {
+		end = start + uint32(values[1])
+	}
+	if end > 0x10000 {
+		end = 0x10000
+	}
+	for row := start; row < end; row += 16 {
+		fmt.Fprintf(d.writer, "0x%04X:", row)
+		text := make([]byte, 0, 16)
+		for address := row; address < row+16 && address < end; address++ {
+			value := d.memory.Read(uint16(address))
+			fmt.Fprintf(d.writer, " %02X", value)
+			if value >= 0x20 && value < 0x7F {
+				text = append(text, value)
+			} else {
+				text = append(text, '.')
+			}
+		}
+		fmt.Fprintf(d.writer, "  %s\n", text)
+	}
+}
+
+func (d *Debugger) set(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(d.writer, "expected 2 arguments, found %d\n", len(args))
+		return
+	}
+	value, err := strconv.ParseUint(args[1], 0, 8)
+	if err != nil {
+		fmt.Fprintf(d.writer, "invalid byte value %q\n", args[1])
+		return
+	}
+
+	target := strings.ToUpper(args[0])
+	if strings.HasPrefix(target, "R") {
+		register, err := strconv.ParseUint(target[1:], 10, 8)
+		if err != nil || register >= uint64(processor.RegisterCount) {
+			fmt.Fprintf(d.writer, "invalid register %q\n", args[0])
+			return
+		}
+		d.proc.SetRegisterValue(uint8(register), uint8(value))
+		return
+	}
+
+	address, ok := d.parseArgs(args[:1], 1, 1)
+	if ok {
+		d.memory.Write(address[0], uint8(value))
+		d.proc.InvalidateDecodeCache()
+	}
+}
@@ -0,0 +1,98 @@
+package debugger_test
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/debugger"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+var testProgram = []uint8{
+	processor.MoveLitReg, 0x2A, 0x01, // 0x0000
+	processor.Inc, 0x01, // 0x0003
+	processor.Inc, 0x01, // 0x0005
+	processor.Halt, // 0x0007
+}
+
+func runDebugger(t *testing.T, commands string) (string, *processor.Processor, *memory.Memory, int) {
+	t.Helper()
+	m := memory.New()
+	m.LoadProgram(testProgram)
+	p := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+	)
+	var out strings.Builder
+	d := debugger.New(p, m, bufio.NewReader(strings.NewReader(commands)), &out)
+	status := d.Run()
+	return out.String(), p, m, status
+}
+
+func checkOutputContains(t *testing.T, output string, expected ...string) {
+	t.Helper()
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("output missing %q:\n%s", e, output)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	output, p, _, _ := runDebugger(t, "step\ns 2\n")
+	checkOutputContains(t, output,
+		"0x0000: MLR 0x2A, R1",
+		"0x0003: INC R1",
+		"0x0007: HLT",
+	)
+	if p.RegisterValue(1) != 0x2C {
+		t.Errorf("got 0x%X at R1, want 0x2C", p.RegisterValue(1))
+	}
+}
+
+func TestStepPrintsWholeInstruction(t *testing.T) {
+	output, _, _, _ := runDebugger(t, "set 0x0003 0xAD\nset 0x0004 1\nset 0x0005 2\nset 0x0006 3\nstep\n")
+	checkOutputContains(t, output, "0x0003: ADD R1, R2, R3")
+}
+
+func TestBreakpoints(t *testing.T) {
+	output, p, _, status := runDebugger(t, "break 0x0005\nb 7\nlist\ncontinue\nregs\nd 7\nc\nc\n")
+	checkOutputContains(t, output,
+		"0x0005\n0x0007\n",
+		"breakpoint at 0x0005",
+		"R1=0x2B",
+		"IP=0x0005 SP=0xFF00 FP=0xFF00 StackSize=0 Flags=----",
+		"program halted at 0x0008",
+		"program is not running",
+	)
+	if p.InstructionPointer() != 0x0008 || status != 0 {
+		t.Errorf("got IP 0x%X and status %d, want 0x0008 and 0", p.InstructionPointer(), status)
+	}
+}
+
+func TestSetAndHexdump(t *testing.T) {
+	output, p, m, _ := runDebugger(t, "set R3 0x41\nset 0x0010 0x42\nset R9 1\nx 0x0010 2\nquit\nstep\n")
+	if p.RegisterValue(3) != 0x41 {
+		t.Errorf("got 0x%X at R3, want 0x41", p.RegisterValue(3))
+	}
+	if m.Read(0x0010) != 0x42 {
+		t.Errorf("got 0x%X at 0x0010, want 0x42", m.Read(0x0010))
+	}
+	checkOutputContains(t, output, "invalid register \"R9\"", "0x0010: 42 00  B.")
+	if strings.Contains(output, "INC") {
+		t.Error("commands were executed after quit")
+	}
+}
+
+func TestErrorsAreReported(t *testing.T) {
+	output, _, _, status := runDebugger(t, "set 0x0000 0x0F\nstep\n")
+	checkOutputContains(t, output, "error: unknown instruction 0xF at position 0x0")
+	if status != 1 {
+		t.Errorf("got status %d, want 1", status)
+	}
+}
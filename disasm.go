@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/scottmcleodjr/gebvm/disassembler"
+)
+
+const (
+	disasmHelpText string = `Provide the bytecode file to disassemble as an argument.
+
+  Example: ./gebvm disasm -follow hello_world.geb
+
+`
+)
+
+// disasmCommand writes the disassembly listing of a bytecode file to stdout.
+func disasmCommand(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	follow := flags.Bool("follow", false, "follow jump, branch and call targets from the entry address and list unreached bytes as data")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, disasmHelpText)
+		return 1
+	}
+
+	e, err := loadExecutable(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	table, err := loadSymbols(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	var labels disassembler.Labeler
+	if table != nil {
+		labels = table
+	}
+
+	var lines []disassembler.Line
+	if *follow {
+		segments := make([]disassembler.Segment, len(e.Segments))
+		for i, segment := range e.Segments {
+			segments[i] = disassembler.Segment{Address: segment.Address, Data: segment.Data}
+		}
+		for _, segmentLines := range disassembler.RecursiveSegments(segments, e.Entry) {
+			lines = append(lines, segmentLines...)
+		}
+	} else {
+		for _, segment := range e.Segments {
+			lines = append(lines, disassembler.Linear(segment.Data, segment.Address)...)
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = disassembler.WriteWithLabels(w, lines, labels)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing listing: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,299 @@
+// Package disassembler translates GebVM bytecode into a listing that can
+// be read back in by the assembler.
+package disassembler
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+const maxDataLineBytes = 8
+
+// Line is a single decoded instruction or a run of data bytes.
+type Line struct {
+	Address     uint16
+	Bytes       []uint8
+	Instruction *processor.InstructionInfo // nil for data
+	Operands    []uint16                   // Decoded operand values
+}
+
+// Labeler returns the label for an address, such as a symbols.Table.
+type Labeler interface {
+	LabelAt(address uint16) (string, bool)
+}
+
+// Text returns the assembly text of the line.
+func (l Line) Text() string {
+	return l.TextWithLabels(nil)
+}
+
+// TextWithLabels returns the assembly text of the line with labels in
+// place of address operands that have one.  labels can be nil.
+func (l Line) TextWithLabels(labels Labeler) string {
+	if l.Instruction == nil {
+		values := make([]string, len(l.Bytes))
+		for i, value := range l.Bytes {
+			values[i] = fmt.Sprintf("0x%02X", value)
+		}
+		return ".byte " + strings.Join(values, ", ")
+	}
+
+	if len(l.Operands) == 0 {
+		return l.Instruction.Mnemonic
+	}
+	operands := make([]string, len(l.Operands))
+	for i, kind := range l.Instruction.Operands {
+		operands[i] = formatOperand(kind, l.Operands[i])
+		if kind == processor.OperandAddress && labels != nil {
+			if label, found := labels.LabelAt(l.Operands[i]); found {
+				operands[i] = label
+			}
+		}
+	}
+	return l.Instruction.Mnemonic + " " + strings.Join(operands, ", ")
+}
+
+// String returns the listing text of the line.  The address and raw
+// bytes are written as a comment so the listing can be assembled.
+func (l Line) String() string {
+	return l.format(nil)
+}
+
+func (l Line) format(labels Labeler) string {
+	return fmt.Sprintf("        %-32s ; %04X: % X", l.TextWithLabels(labels), l.Address, l.Bytes)
+}
+
+func formatOperand(kind processor.OperandKind, value uint16) string {
+	switch kind {
+	case processor.OperandRegister:
+		return fmt.Sprintf("R%d", value)
+	case processor.OperandAddress, processor.OperandWord:
+		return fmt.Sprintf("0x%04X", value)
+	case processor.OperandOffset:
+		return fmt.Sprint(int8(value))
+	}
+	return fmt.Sprintf("0x%02X", value)
+}
+
+// decode returns the instruction at offset in image, or false if the
+// bytes there are not a complete and valid instruction.
+func decode(image []uint8, origin uint16, offset int) (Line, bool) {
+	info, found := processor.LookupOpcode(image[offset])
+	if !found || offset+int(info.Size()) > len(image) {
+		return Line{}, false
+	}
+
+	line := Line{
+		Address:     origin + uint16(offset),
+		Bytes:       image[offset : offset+int(info.Size())],
+		Instruction: &info,
+	}
+	next := offset + 1
+	for _, kind := range info.Operands {
+		value := uint16(image[next])
+		if kind.Size() == 2 {
+			value = (value << 8) + uint16(image[next+1])
+		}
+		if kind == processor.OperandRegister && value >= uint16(processor.RegisterCount) {
+			return Line{}, false
+		}
+		line.Operands = append(line.Operands, value)
+		next += int(kind.Size())
+	}
+	return line, true
+}
+
+// Linear decodes image, loaded at origin, as a sequence of instructions.
+// Bytes that are not valid instructions are returned as data.
+func Linear(image []uint8, origin uint16) []Line {
+	return collect(image, origin, func(offset int) (Line, bool) {
+		return decode(image, origin, offset)
+	})
+}
+
+// Segment is a run of bytes loaded at an address, such as a segment of
+// an executable.
+type Segment struct {
+	Address uint16
+	Data    []uint8
+}
+
+// Recursive decodes image, loaded at origin, by following the control
+// flow from each entry address.  Jump, branch and call targets are
+// followed, and bytes that are never reached are returned as data.  The
+// origin is used when no entries are given.
+func Recursive(image []uint8, origin uint16, entries ...uint16) []Line {
+	if len(entries) == 0 {
+		entries = []uint16{origin}
+	}
+	return RecursiveSegments([]Segment{{Address: origin, Data: image}}, entries...)[0]
+}
+
+// RecursiveSegments decodes segments like Recursive, following the
+// control flow from each entry address across all of them, so a segment
+// reached only by a jump or call from another segment is decoded too.
+// It returns the lines of each segment in the order of segments.
+func RecursiveSegments(segments []Segment, entries ...uint16) [][]Line {
+	instructions := make([]map[int]Line, len(segments))
+	claimed := make([][]bool, len(segments))
+	for i, segment := range segments {
+		instructions[i] = map[int]Line{}
+		claimed[i] = make([]bool, len(segment.Data))
+	}
+	pending := append([]uint16(nil), entries...)
+
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		i := segmentAt(segments, address)
+		if i < 0 {
+			continue
+		}
+		image, origin := segments[i].Data, segments[i].Address
+
+		for offset := int(address) - int(origin); offset < len(image); {
+			if _, done := instructions[i][offset]; done {
+				break
+			}
+			line, ok := decode(image, origin, offset)
+			if !ok || isClaimed(claimed[i], offset, len(line.Bytes)) {
+				break
+			}
+			instructions[i][offset] = line
+			for j := range line.Bytes {
+				claimed[i][offset+j] = true
+			}
+
+			if target := line.Instruction.Target(); target >= 0 {
+				pending = append(pending, line.Operands[target])
+			}
+			flow := line.Instruction.Flow
+			if flow == processor.FlowJump || flow == processor.FlowReturn || flow == processor.FlowHalt {
+				break
+			}
+			offset += len(line.Bytes)
+		}
+	}
+
+	lines := make([][]Line, len(segments))
+	for i, segment := range segments {
+		lines[i] = collect(segment.Data, segment.Address, func(offset int) (Line, bool) {
+			line, found := instructions[i][offset]
+			return line, found
+		})
+	}
+	return lines
+}
+
+// segmentAt returns the index of the segment holding address, or -1.
+func segmentAt(segments []Segment, address uint16) int {
+	for i, segment := range segments {
+		if int(address) >= int(segment.Address) && int(address) < int(segment.Address)+len(segment.Data) {
+			return i
+		}
+	}
+	return -1
+}
+
+// collect returns the lines for image, using instructionAt to find the
+// instruction at each offset.  Offsets without one are returned as data.
+func collect(image []uint8, origin uint16, instructionAt func(int) (Line, bool)) []Line {
+	var lines []Line
+	dataStart := -1
+	for offset := 0; offset < len(image); {
+		line, found := instructionAt(offset)
+		if !found {
+			if dataStart < 0 {
+				dataStart = offset
+			}
+			offset++
+			if offset-dataStart == maxDataLineBytes {
+				lines = append(lines, dataLine(image, origin, dataStart, offset))
+				dataStart = -1
+			}
+			continue
+		}
+		if dataStart >= 0 {
+			lines = append(lines, dataLine(image, origin, dataStart, offset))
+			dataStart = -1
+		}
+		lines = append(lines, line)
+		offset += len(line.Bytes)
+	}
+	if dataStart >= 0 {
+		lines = append(lines, dataLine(image, origin, dataStart, len(image)))
+	}
+	return lines
+}
+
+func isClaimed(claimed []bool, offset, size int) bool {
+	for i := offset; i < offset+size; i++ {
+		if claimed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func dataLine(image []uint8, origin uint16, start, end int) Line {
+	return Line{Address: origin + uint16(start), Bytes: image[start:end]}
+}
+
+// Write writes the listing for lines to w.  An .org directive is written
+// before any line that does not follow the previous line.
+func Write(w io.Writer, lines []Line) error {
+	return WriteWithLabels(w, lines, nil)
+}
+
+// WriteWithLabels writes the listing for lines to w like Write, and also
+// writes a label before each address that has one.  Data lines are split
+// so that every label starts a line.  labels can be nil.
+func WriteWithLabels(w io.Writer, lines []Line, labels Labeler) error {
+	next := 0x0000
+	for _, line := range splitAtLabels(lines, labels) {
+		if int(line.Address) != next {
+			if _, err := fmt.Fprintf(w, "        .org 0x%04X\n", line.Address); err != nil {
+				return err
+			}
+		}
+		if labels != nil {
+			if label, found := labels.LabelAt(line.Address); found {
+				if _, err := fmt.Fprintf(w, "%s:\n", label); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(w, line.format(labels)); err != nil {
+			return err
+		}
+		next = int(line.Address) + len(line.Bytes)
+	}
+	return nil
+}
+
+// splitAtLabels splits data lines at each labelled address after their
+// first byte.
+func splitAtLabels(lines []Line, labels Labeler) []Line {
+	if labels == nil {
+		return lines
+	}
+	var out []Line
+	for _, line := range lines {
+		if line.Instruction != nil {
+			out = append(out, line)
+			continue
+		}
+		start := 0
+		for i := 1; i < len(line.Bytes); i++ {
+			if _, found := labels.LabelAt(line.Address + uint16(i)); found {
+				out = append(out, Line{Address: line.Address + uint16(start), Bytes: line.Bytes[start:i]})
+				start = i
+			}
+		}
+		out = append(out, Line{Address: line.Address + uint16(start), Bytes: line.Bytes[start:]})
+	}
+	return out
+}
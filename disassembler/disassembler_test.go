@@ -0,0 +1,207 @@
+package disassembler_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/assembler"
+	"github.com/scottmcleodjr/gebvm/disassembler"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func checkLineTexts(t *testing.T, lines []disassembler.Line, expected []string) {
+	t.Helper()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(expected))
+	}
+	for i, line := range lines {
+		if line.Text() != expected[i] {
+			t.Errorf("got %q at line %d, want %q", line.Text(), i, expected[i])
+		}
+	}
+}
+
+func TestLinear(t *testing.T) {
+	lines := disassembler.Linear([]uint8{
+		processor.MoveLitReg, 0x2A, 0x01,
+		processor.JumpEqual, 0x02, 0x12, 0x34,
+		processor.MoveLitWord, 0xBE, 0xEF, 0x04,
+		processor.SubtractDst, 0x01, 0x02, 0x03,
+		0x0F,                             // Unknown instruction
+		processor.MoveRegReg, 0x01, 0x09, // Invalid register
+		processor.Call, 0xAB, // Truncated
+	}, 0x0000)
+	checkLineTexts(t, lines, []string{
+		"MLR 0x2A, R1",
+		"JEQ R2, 0x1234",
+		"MLW 0xBEEF, R4",
+		"SUB R1, R2, R3",
+		".byte 0x0F, 0x02, 0x01, 0x09, 0x83, 0xAB",
+	})
+	if lines[1].Address != 0x0003 || !bytes.Equal(lines[1].Bytes, []uint8{0x61, 0x02, 0x12, 0x34}) {
+		t.Errorf("got %04X: % X, want 0003: 61 02 12 34", lines[1].Address, lines[1].Bytes)
+	}
+	if lines[1].String() != "        JEQ R2, 0x1234                   ; 0003: 61 02 12 34" {
+		t.Errorf("got %q", lines[1].String())
+	}
+}
+
+func TestLinearSplitsLongData(t *testing.T) {
+	lines := disassembler.Linear(bytes.Repeat([]uint8{0x0F}, 10), 0x0100)
+	if len(lines) != 2 || len(lines[0].Bytes) != 8 || lines[1].Address != 0x0108 {
+		t.Errorf("got %v, want 8 and 2 byte data lines", lines)
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	program, err := ioutil.ReadFile("../examples/hello_world.geb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLineTexts(t, disassembler.Recursive(program, 0x0000), []string{
+		"PNT 0x0005, 0x0E",
+		"HLT",
+		".byte 0x48, 0x65, 0x6C, 0x6C, 0x6F, 0x2C, 0x20, 0x57",
+		".byte 0x6F, 0x72, 0x6C, 0x64, 0x21, 0x0A",
+	})
+}
+
+func TestRecursiveFollowsTargets(t *testing.T) {
+	lines := disassembler.Recursive([]uint8{
+		processor.Jump, 0x00, 0x04, // 0x0000
+		processor.Noop,                        // 0x0003 is skipped
+		processor.JumpEqual, 0x01, 0x00, 0x0C, // 0x0004
+		processor.Call, 0x00, 0x0D, // 0x0008
+		processor.Halt,   // 0x000B
+		processor.Halt,   // 0x000C
+		processor.Return, // 0x000D
+		processor.Noop,   // 0x000E is skipped
+	}, 0x0000)
+	checkLineTexts(t, lines, []string{
+		"JMP 0x0004",
+		".byte 0x00",
+		"JEQ R1, 0x000C",
+		"CLL 0x000D",
+		"HLT",
+		"HLT",
+		"RET",
+		".byte 0x00",
+	})
+}
+
+func TestRecursiveSegments(t *testing.T) {
+	lines := disassembler.RecursiveSegments([]disassembler.Segment{
+		{Address: 0x0000, Data: []uint8{
+			processor.Call, 0x80, 0x00, // 0x0000
+			processor.Halt, // 0x0003
+			processor.Noop, // 0x0004 is skipped
+		}},
+		{Address: 0x8000, Data: []uint8{
+			processor.MoveLitReg, 0x2A, 0x01, // 0x8000 is only reached by the call
+			processor.Return, // 0x8003
+		}},
+		{Address: 0x9000, Data: []uint8{processor.Return}}, // Never reached
+	}, 0x0000)
+	if len(lines) != 3 {
+		t.Fatalf("got %d segments, want 3", len(lines))
+	}
+	checkLineTexts(t, lines[0], []string{"CLL 0x8000", "HLT", ".byte 0x00"})
+	checkLineTexts(t, lines[1], []string{"MLR 0x2A, R1", "RET"})
+	checkLineTexts(t, lines[2], []string{".byte 0x84"})
+}
+
+func TestWriteCanBeAssembled(t *testing.T) {
+	for _, filename := range []string{"../examples/hello_world.geb", "../examples/invalid_instruction.geb"} {
+		program, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, lines := range [][]disassembler.Line{
+			disassembler.Linear(program, 0x0000),
+			disassembler.Recursive(program, 0x0000),
+		} {
+			var listing strings.Builder
+			if err := disassembler.Write(&listing, lines); err != nil {
+				t.Fatal(err)
+			}
+			assembled, err := assembler.Assemble(filename, []byte(listing.String()))
+			if err != nil {
+				t.Fatalf("got error %q assembling listing:\n%s", err, listing.String())
+			}
+			if !bytes.Equal(assembled.Image, program) {
+				t.Errorf("got % X from listing, want % X", assembled.Image, program)
+			}
+		}
+	}
+}
+
+func TestFrameOffsetsRoundTrip(t *testing.T) {
+	program := []uint8{
+		processor.LoadFrame, 0xF6, 0x03,
+		processor.StoreFrame, 0x02, 0x7F,
+		processor.LoadFrame, 0x80, 0x01,
+		processor.StoreFrame, 0x00, 0x00,
+	}
+	lines := disassembler.Linear(program, 0x0000)
+	checkLineTexts(t, lines, []string{"LDF -10, R3", "STF R2, 127", "LDF -128, R1", "STF R0, 0"})
+
+	var listing strings.Builder
+	if err := disassembler.Write(&listing, lines); err != nil {
+		t.Fatal(err)
+	}
+	assembled, err := assembler.Assemble("frame.asm", []byte(listing.String()))
+	if err != nil {
+		t.Fatalf("got error %q assembling listing:\n%s", err, listing.String())
+	}
+	if !bytes.Equal(assembled.Image, program) {
+		t.Errorf("got % X from listing, want % X", assembled.Image, program)
+	}
+}
+
+func TestWriteOrigin(t *testing.T) {
+	var listing strings.Builder
+	disassembler.Write(&listing, disassembler.Linear([]uint8{processor.Halt}, 0x0100))
+	if !strings.HasPrefix(listing.String(), "        .org 0x0100\n") {
+		t.Errorf("got %q, want .org 0x0100 first", listing.String())
+	}
+}
+
+func TestWriteSegments(t *testing.T) {
+	lines := disassembler.Linear([]uint8{processor.Noop}, 0x0000)
+	lines = append(lines, disassembler.Linear([]uint8{processor.Halt}, 0x0010)...)
+	var listing strings.Builder
+	disassembler.Write(&listing, lines)
+	if strings.Count(listing.String(), ".org") != 1 || !strings.Contains(listing.String(), "        .org 0x0010\n") {
+		t.Errorf("got %q, want a single .org 0x0010", listing.String())
+	}
+}
+
+type testLabels map[uint16]string
+
+func (l testLabels) LabelAt(address uint16) (string, bool) {
+	label, found := l[address]
+	return label, found
+}
+
+func TestWriteWithLabels(t *testing.T) {
+	image := []uint8{
+		processor.Jump, 0x00, 0x05, // 0x0000
+		0x01, 0x02, // 0x0003
+		processor.Halt, // 0x0005
+	}
+	lines := disassembler.Recursive(image, 0x0000)
+	var listing strings.Builder
+	disassembler.WriteWithLabels(&listing, lines, testLabels{0x0004: "data", 0x0005: "end"})
+	expected := "" +
+		"        JMP end                          ; 0000: 60 00 05\n" +
+		"        .byte 0x01                       ; 0003: 01\n" +
+		"data:\n" +
+		"        .byte 0x02                       ; 0004: 02\n" +
+		"end:\n" +
+		"        HLT                              ; 0005: FF\n"
+	if listing.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", listing.String(), expected)
+	}
+}
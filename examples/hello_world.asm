@@ -0,0 +1,5 @@
+; Assembles to hello_world.geb
+        PNT message, 14
+        HLT
+message:
+        .string "Hello, World!\n"
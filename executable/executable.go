@@ -0,0 +1,187 @@
+// Package executable reads and writes GebVM executable files.
+//
+// An executable starts with a header followed by its segments.  Multi-byte
+// values are written high byte first, like addresses in bytecode.
+//
+//	Magic        4 bytes  "GEBX"
+//	Version      1 byte   Format version, currently 1
+//	ISAVersion   1 byte   Instruction set version the program was built for
+//	Entry        2 bytes  Address of the first instruction
+//	SegmentCount 2 bytes
+//
+// Each segment is its load address (2 bytes), its length (4 bytes) and
+// then its data.  Files without the magic are legacy raw bytecode, which
+// is loaded at address 0x0000 and started there.
+package executable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+const (
+	Magic         string = "GEBX"
+	FormatVersion uint8  = 1
+)
+
+// Segment is data loaded into memory at Address.
+type Segment struct {
+	Address uint16
+	Data    []uint8
+}
+
+// End returns the address after the last byte of the segment.
+func (s Segment) End() int {
+	return int(s.Address) + len(s.Data)
+}
+
+// Executable is a program and the addresses it is loaded at.
+type Executable struct {
+	ISAVersion uint8
+	Entry      uint16
+	Segments   []Segment
+}
+
+// Legacy returns the Executable for raw bytecode.
+func Legacy(program []uint8) *Executable {
+	return &Executable{
+		ISAVersion: processor.ISAVersion,
+		Segments:   []Segment{{Address: 0x0000, Data: program}},
+	}
+}
+
+// IsExecutable reports whether data starts with the executable magic.
+func IsExecutable(data []uint8) bool {
+	return bytes.HasPrefix(data, []uint8(Magic))
+}
+
+type header struct {
+	Magic        [4]uint8
+	Version      uint8
+	ISAVersion   uint8
+	Entry        uint16
+	SegmentCount uint16
+}
+
+type segmentHeader struct {
+	Address uint16
+	Length  uint32
+}
+
+// Decode reads an executable, or raw bytecode if data does not start
+// with the executable magic.
+func Decode(data []uint8) (*Executable, error) {
+	if !IsExecutable(data) {
+		return Legacy(data), nil
+	}
+
+	r := bytes.NewReader(data)
+	var h header
+	if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+		return nil, errors.New("executable header is truncated")
+	}
+	if h.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported executable format version %d", h.Version)
+	}
+
+	e := &Executable{ISAVersion: h.ISAVersion, Entry: h.Entry}
+	for i := 0; i < int(h.SegmentCount); i++ {
+		var sh segmentHeader
+		if err := binary.Read(r, binary.BigEndian, &sh); err != nil {
+			return nil, fmt.Errorf("segment %d header is truncated", i)
+		}
+		if int(sh.Length) > r.Len() {
+			return nil, fmt.Errorf("segment %d is truncated", i)
+		}
+		segment := Segment{Address: sh.Address, Data: make([]uint8, sh.Length)}
+		r.Read(segment.Data)
+		e.Segments = append(e.Segments, segment)
+	}
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the last segment", r.Len())
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Encode returns the executable file contents.
+func (e *Executable) Encode() ([]uint8, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	h := header{
+		Version:      FormatVersion,
+		ISAVersion:   e.ISAVersion,
+		Entry:        e.Entry,
+		SegmentCount: uint16(len(e.Segments)),
+	}
+	copy(h.Magic[:], Magic)
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, h)
+	for _, s := range e.Segments {
+		binary.Write(&b, binary.BigEndian, segmentHeader{Address: s.Address, Length: uint32(len(s.Data))})
+		b.Write(s.Data)
+	}
+	return b.Bytes(), nil
+}
+
+// Validate checks that the segments fit in memory without overlapping
+// and that the instruction set version is supported by the processor.
+func (e *Executable) Validate() error {
+	if e.ISAVersion > processor.ISAVersion {
+		return fmt.Errorf("executable requires instruction set version %d, processor supports %d",
+			e.ISAVersion, processor.ISAVersion)
+	}
+	if len(e.Segments) > 0xFFFF {
+		return errors.New("executable has more than 65535 segments")
+	}
+
+	segments := make([]Segment, len(e.Segments))
+	copy(segments, e.Segments)
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Address < segments[j].Address })
+	for i, s := range segments {
+		if s.End() > memory.MemorySize {
+			return fmt.Errorf("segment at 0x%04X exceeds available memory", s.Address)
+		}
+		if i > 0 && segments[i-1].End() > int(s.Address) {
+			return fmt.Errorf("segment at 0x%04X overlaps segment at 0x%04X", s.Address, segments[i-1].Address)
+		}
+	}
+	return nil
+}
+
+// segmentLoader is implemented by memory devices that can load data at
+// an address, such as memory.Memory and bus.Bus.
+type segmentLoader interface {
+	LoadAt(address uint16, data []uint8) error
+}
+
+// Load loads every segment into m.  Devices without a LoadAt method are
+// loaded with Write.
+func (e *Executable) Load(m processor.MemoryDevice) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	loader, canLoad := m.(segmentLoader)
+	for _, s := range e.Segments {
+		if canLoad {
+			if err := loader.LoadAt(s.Address, s.Data); err != nil {
+				return err
+			}
+			continue
+		}
+		for i, value := range s.Data {
+			m.Write(s.Address+uint16(i), value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package executable_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/bus"
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func testExecutable() *executable.Executable {
+	return &executable.Executable{
+		ISAVersion: processor.ISAVersion,
+		Entry:      0x0100,
+		Segments: []executable.Segment{
+			{Address: 0x0100, Data: []uint8{processor.Print, 0x20, 0x00, 0x03, processor.Halt}},
+			{Address: 0x2000, Data: []uint8("hi\n")},
+		},
+	}
+}
+
+func TestEncodeAndDecode(t *testing.T) {
+	data, err := testExecutable().Encode()
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if !executable.IsExecutable(data) {
+		t.Errorf("got false, want encoded data to be an executable")
+	}
+	e, err := executable.Decode(data)
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if !reflect.DeepEqual(e, testExecutable()) {
+		t.Errorf("got %+v, want %+v", e, testExecutable())
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	program := []uint8{processor.Noop, processor.Halt}
+	e, err := executable.Decode(program)
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if e.Entry != 0x0000 || len(e.Segments) != 1 || e.Segments[0].Address != 0x0000 ||
+		!bytes.Equal(e.Segments[0].Data, program) {
+		t.Errorf("got %+v, want program loaded and started at 0x0000", e)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid, _ := testExecutable().Encode()
+	badVersion := append([]uint8{}, valid...)
+	badVersion[4] = 0x02
+	newerISA := append([]uint8{}, valid...)
+	newerISA[5] = processor.ISAVersion + 1
+
+	tests := []struct {
+		data           []uint8
+		messageContent string
+	}{
+		{data: []uint8("GEBX"), messageContent: "header is truncated"},
+		{data: badVersion, messageContent: "unsupported executable format version 2"},
+		{data: newerISA, messageContent: "requires instruction set version"},
+		{data: valid[:len(valid)-1], messageContent: "segment 1 is truncated"},
+		{data: append(valid, 0x00), messageContent: "unexpected bytes"},
+	}
+	for _, test := range tests {
+		_, err := executable.Decode(test.data)
+		if err == nil || !strings.Contains(err.Error(), test.messageContent) {
+			t.Errorf("got %v, want error containing %q", err, test.messageContent)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		segments       []executable.Segment
+		messageContent string
+	}{
+		{
+			segments:       []executable.Segment{{Address: 0xFFFF, Data: []uint8{0x01, 0x02}}},
+			messageContent: "segment at 0xFFFF exceeds available memory",
+		},
+		{
+			segments: []executable.Segment{
+				{Address: 0x0010, Data: []uint8{0x01}},
+				{Address: 0x0000, Data: make([]uint8, 0x11)},
+			},
+			messageContent: "segment at 0x0010 overlaps segment at 0x0000",
+		},
+	}
+	for _, test := range tests {
+		e := &executable.Executable{ISAVersion: processor.ISAVersion, Segments: test.segments}
+		err := e.Validate()
+		if err == nil || err.Error() != test.messageContent {
+			t.Errorf("got %v, want %q", err, test.messageContent)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	m := memory.New()
+	if err := testExecutable().Load(m); err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if m.Read(0x0104) != processor.Halt || m.Read(0x2001) != 'i' {
+		t.Errorf("got 0x%02X at 0x0104 and 0x%02X at 0x2001, want segments loaded", m.Read(0x0104), m.Read(0x2001))
+	}
+
+	// Segments can be loaded into ROM on a bus
+	rom := bus.NewROM(0x100)
+	b, _ := bus.New(
+		bus.Mapping{Start: 0x0100, End: 0x01FF, Device: rom},
+		bus.Mapping{Start: 0x2000, End: 0x20FF, Device: bus.NewRAM(0x100)},
+	)
+	if err := testExecutable().Load(b); err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if b.Read(0x0100) != processor.Print || b.Read(0x2000) != 'h' {
+		t.Errorf("got 0x%02X at 0x0100 and 0x%02X at 0x2000, want segments loaded", b.Read(0x0100), b.Read(0x2000))
+	}
+}
@@ -24,11 +24,14 @@ func (m *Memory) Read(address uint16) uint8 {
 }
 
 func (m *Memory) LoadProgram(program []uint8) error {
-	if len(program) > MemorySize {
+	return m.LoadAt(0x0000, program)
+}
+
+// LoadAt writes data to memory starting at address.
+func (m *Memory) LoadAt(address uint16, data []uint8) error {
+	if int(address)+len(data) > MemorySize {
 		return errors.New("program length exceeds available memory")
 	}
-	for i, instruction := range program {
-		m.Write(uint16(i), instruction)
-	}
+	copy(m.memory[address:], data)
 	return nil
 }
@@ -42,3 +42,16 @@ func TestLoadProgram(t *testing.T) {
 		t.Error("got nil, want error for program length exceeding available memory")
 	}
 }
+
+func TestLoadAt(t *testing.T) {
+	m := memory.New()
+	if err := m.LoadAt(0xFFFE, []uint8{0x01, 0x02}); err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if m.Read(0xFFFE) != 0x01 || m.Read(0xFFFF) != 0x02 {
+		t.Errorf("got 0x%X 0x%X at 0xFFFE, want 0x01 0x02", m.Read(0xFFFE), m.Read(0xFFFF))
+	}
+	if err := m.LoadAt(0xFFFF, []uint8{0x01, 0x02}); err == nil {
+		t.Error("got nil, want error for data exceeding available memory")
+	}
+}
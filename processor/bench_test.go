@@ -0,0 +1,76 @@
+package processor_test
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func benchmarkSteps(b *testing.B, program []uint8) {
+	p, _ := newTestProcessorWithPogram(program)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !p.Step() {
+			b.Fatalf("stopped with errors %v", p.Errors())
+		}
+	}
+}
+
+func BenchmarkStepArithmetic(b *testing.B) {
+	benchmarkSteps(b, []uint8{
+		processor.Inc, R2,
+		processor.Add, R2, R3,
+		processor.MoveRegReg, R0, R3,
+		processor.Jump, 0x00, 0x00,
+	})
+}
+
+func BenchmarkStepCall(b *testing.B) {
+	benchmarkSteps(b, []uint8{
+		processor.Call, 0x00, 0x06,
+		processor.Jump, 0x00, 0x00,
+		processor.Inc, R2,
+		processor.Return,
+	})
+}
+
+// Writing to code discards its decoded instructions
+func BenchmarkStepSelfModifying(b *testing.B) {
+	benchmarkSteps(b, []uint8{
+		processor.Inc, R2,
+		processor.MoveLitReg, 0x00, R4,
+		processor.MoveLitReg, 0x01, R5,
+		processor.MoveLitMem, R2, R4,
+		processor.Jump, 0x00, 0x00,
+	})
+}
+
+func BenchmarkRun(b *testing.B) {
+	// Nested loops executing 130,563 instructions
+	program := []uint8{
+		processor.MoveLitReg, 0xFF, R2,
+		processor.MoveLitReg, 0xFF, R3,
+		processor.Dec, R3,
+		processor.JumpNotEqual, R3, 0x00, 0x06,
+		processor.Dec, R2,
+		processor.JumpNotEqual, R2, 0x00, 0x03,
+		processor.Halt,
+	}
+	for i := 0; i < b.N; i++ {
+		m := memory.New()
+		m.LoadProgram(program)
+		p := processor.New(
+			m,
+			bufio.NewReader(os.Stdin),
+			bufio.NewWriter(ioutil.Discard),
+			bufio.NewWriter(ioutil.Discard),
+		)
+		if status := p.Run(); status != 0 {
+			b.Fatalf("got status %d, want 0", status)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package processor
+
+// maxOperandBytes is the largest number of operand bytes an instruction
+// can have.
+const maxOperandBytes = 7
+
+// decodedInstruction is an instruction read from memory ahead of its
+// execution.  Only instructions that cannot fault while they are fetched
+// are decoded, so executing one never needs to check the memory bounds or
+// protection of its bytes.
+type decodedInstruction struct {
+	execute  instruction
+	operands [maxOperandBytes]uint8
+	size     uint8 // 0 if the entry is not decoded
+}
+
+// decodePage holds the decoded instructions starting in 256 addresses.
+type decodePage [0xFF + 1]decodedInstruction
+
+// VolatileMemoryDevice is implemented by memory devices whose contents
+// can change without a write from the Processor, such as memory with
+// switchable banks.  Generation must return a new value after any such
+// change, which clears the decode cache.
+type VolatileMemoryDevice interface {
+	MemoryDevice
+	Generation() uint64
+}
+
+// InvalidateDecodeCache discards pre-decoded instructions.  It must be
+// called after writing memory that may hold code without going through
+// the Processor, such as from a debugger.
+func (p *Processor) InvalidateDecodeCache() {
+	p.decodeCache = [0xFF + 1]*decodePage{}
+}
+
+// decoded returns the instruction at address, decoding it if needed, or
+// nil if it must be fetched a byte at a time.
+func (p *Processor) decoded(address uint16) *decodedInstruction {
+	if p.volatile != nil {
+		if generation := p.volatile.Generation(); generation != p.generation {
+			p.generation = generation
+			p.InvalidateDecodeCache()
+		}
+	}
+
+	page := p.decodeCache[address>>8]
+	if page == nil {
+		page = new(decodePage)
+		p.decodeCache[address>>8] = page
+	}
+	d := &page[address&0xFF]
+	if d.size != 0 {
+		return d
+	}
+
+	opcode := p.memory.Read(address)
+	execute := instructions[opcode]
+	size := instructionSizes[opcode]
+	// Fetching a byte from 0xFFFF moves the IP out of bounds
+	if execute == nil || int(address)+int(size) > 0xFFFF {
+		return nil
+	}
+	if p.protection != nil {
+		for i := uint16(0); i < uint16(size); i++ {
+			if p.protection[address+i]&(NoExecute|StackOnly) != 0 {
+				return nil
+			}
+		}
+	}
+	for i := uint16(1); i < uint16(size); i++ {
+		d.operands[i-1] = p.memory.Read(address + i)
+	}
+	d.execute = execute
+	d.size = size
+	return d
+}
+
+// invalidateDecoded discards the decoded instructions that include the
+// byte at address.
+func (p *Processor) invalidateDecoded(address uint16) {
+	first := address - maxOperandBytes
+	if p.decodeCache[address>>8] == nil && p.decodeCache[first>>8] == nil {
+		return // Such as writes to the stack
+	}
+	for i := uint16(0); i <= maxOperandBytes; i++ {
+		start := address - i
+		if page := p.decodeCache[start>>8]; page != nil {
+			page[start&0xFF].size = 0
+		}
+	}
+}
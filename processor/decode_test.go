@@ -0,0 +1,92 @@
+package processor_test
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestSelfModifyingCode(t *testing.T) {
+	// The loop increments R2, then rewrites the INC operand to R3
+	p, _ := newTestProcessorWithPogram([]uint8{
+		processor.Inc, R2,
+		processor.MoveLitReg, 0x00, R4,
+		processor.MoveLitReg, 0x01, R5,
+		processor.MoveLitMem, R3, R4,
+		processor.Jump, 0x00, 0x00,
+	})
+	for i := 0; i < 6; i++ {
+		p.Step()
+	}
+	if p.RegisterValue(R2) != 1 || p.RegisterValue(R3) != 1 {
+		t.Errorf("got R2 %d and R3 %d, want 1 and 1", p.RegisterValue(R2), p.RegisterValue(R3))
+	}
+}
+
+func TestInvalidateDecodeCache(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{
+		processor.Inc, R2,
+		processor.Jump, 0x00, 0x00,
+	})
+	p.Step()
+	p.Step()
+	m.Write(0x0001, R3)
+	p.InvalidateDecodeCache()
+	p.Step()
+	if p.RegisterValue(R2) != 1 || p.RegisterValue(R3) != 1 {
+		t.Errorf("got R2 %d and R3 %d, want 1 and 1", p.RegisterValue(R2), p.RegisterValue(R3))
+	}
+}
+
+// volatileMemory changes its generation on every write, like memory with
+// switchable banks.
+type volatileMemory struct {
+	*memory.Memory
+	generation uint64
+}
+
+func (v *volatileMemory) Generation() uint64 {
+	return v.generation
+}
+
+func TestVolatileMemoryDevice(t *testing.T) {
+	m := &volatileMemory{Memory: memory.New()}
+	m.LoadProgram([]uint8{
+		processor.Inc, R2,
+		processor.Jump, 0x00, 0x00,
+	})
+	p := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+	)
+	p.Step()
+	p.Step()
+	m.Write(0x0001, R3)
+	m.generation++
+	p.Step()
+	if p.RegisterValue(R2) != 1 || p.RegisterValue(R3) != 1 {
+		t.Errorf("got R2 %d and R3 %d, want 1 and 1", p.RegisterValue(R2), p.RegisterValue(R3))
+	}
+}
+
+func TestRestoreInvalidatesDecodeCache(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{
+		processor.Inc, R2,
+		processor.Jump, 0x00, 0x00,
+	})
+	snapshot := p.Snapshot()
+	p.Step()
+	snapshot.Memory[0x0001] = R3
+	if err := p.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	p.Step()
+	if m.Read(0x0001) != R3 || p.RegisterValue(R3) != 1 {
+		t.Errorf("got R3 %d, want 1", p.RegisterValue(R3))
+	}
+}
@@ -0,0 +1,123 @@
+package processor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestDestinationForms(t *testing.T) {
+	tests := []struct {
+		instruction uint8
+		right       uint8 // R2, or the literal for literal forms
+		expected    uint8 // R5
+		flags       uint8
+	}{
+		{instruction: processor.LogicalAndDst, right: 0x3C, expected: 0x30, flags: 0},
+		{instruction: processor.LogicalOrDst, right: 0x0F, expected: 0xFF, flags: N},
+		{instruction: processor.LogicalXorDst, right: 0xF0, expected: 0x00, flags: Z},
+		{instruction: processor.LogicalBitClearDst, right: 0x30, expected: 0xC0, flags: N},
+		{instruction: processor.LogicalShiftLeftDst, right: 2, expected: 0xC0, flags: C | N},
+		{instruction: processor.LogicalShiftRightDst, right: 4, expected: 0x0F, flags: 0},
+		{instruction: processor.ArithShiftRightDst, right: 4, expected: 0xFF, flags: N},
+		{instruction: processor.LogicalAndLitDst, right: 0x0F, expected: 0x00, flags: Z},
+		{instruction: processor.LogicalOrLitDst, right: 0x01, expected: 0xF1, flags: N},
+		{instruction: processor.LogicalXorLitDst, right: 0xFF, expected: 0x0F, flags: 0},
+		{instruction: processor.ShiftLeftRegDst, right: 1, expected: 0xE0, flags: C | N},
+		{instruction: processor.ShiftRightRegDst, right: 1, expected: 0x78, flags: 0},
+		{instruction: processor.ArithShiftRegDst, right: 1, expected: 0xF8, flags: N},
+		{instruction: processor.AddDst, right: 0x10, expected: 0x00, flags: Z | C},
+		{instruction: processor.SubtractDst, right: 0x10, expected: 0xE0, flags: N},
+		{instruction: processor.MultiplyDst, right: 2, expected: 0xE0, flags: C | V | N},
+		{instruction: processor.DivideDst, right: 0x10, expected: 0x0F, flags: 0},
+		{instruction: processor.SignedMultiplyDst, right: 2, expected: 0xE0, flags: N},
+		{instruction: processor.SignedDivideDst, right: 0xFE, expected: 0x08, flags: 0},
+		{instruction: processor.SignedRemainderDst, right: 0x07, expected: 0xFE, flags: N},
+		{instruction: processor.ModuloDst, right: 0x07, expected: 0x02, flags: 0},
+		{instruction: processor.AddLitDst, right: 0x20, expected: 0x10, flags: C},
+		{instruction: processor.SubtractLitDst, right: 0xF0, expected: 0x00, flags: Z},
+	}
+
+	for _, test := range tests {
+		info, _ := processor.LookupOpcode(test.instruction)
+		right := R2
+		if info.Operands[1] == processor.OperandLiteral {
+			right = test.right
+		}
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, 0xAA, R0,
+			processor.MoveLitReg, 0xF0, R1,
+			processor.MoveLitReg, test.right, R2,
+			test.instruction, R1, right, R5,
+		})
+		p.Step()
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R5) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%X and %s for %s with 0x%X, want 0x%X and %s",
+				p.RegisterValue(R5), processor.FlagString(p.Flags()), info.Mnemonic,
+				test.right, test.expected, processor.FlagString(test.flags))
+		}
+		if p.RegisterValue(R0) != 0xAA {
+			t.Errorf("got 0x%X at R0 for %s, want it unchanged", p.RegisterValue(R0), info.Mnemonic)
+		}
+		if p.InstructionPointer() != 13 {
+			t.Errorf("got IP 0x%X after %s, want 0xD", p.InstructionPointer(), info.Mnemonic)
+		}
+	}
+}
+
+func TestDestinationFormsWord(t *testing.T) {
+	tests := []struct {
+		instruction uint8
+		expected    uint16 // R6 and R7
+		flags       uint8
+	}{
+		{instruction: processor.AddWordDst, expected: 0x1400, flags: 0},
+		{instruction: processor.SubtractWordDst, expected: 0x11FE, flags: 0},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitWord, 0xAA, 0xAA, R0,
+			processor.MoveLitWord, 0x12, 0xFF, R2,
+			processor.MoveLitWord, 0x01, 0x01, R4,
+			test.instruction, R2, R4, R6,
+		})
+		p.Step()
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if registerPair(p, R6) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%04X and %s for 0x%X, want 0x%04X and %s", registerPair(p, R6),
+				processor.FlagString(p.Flags()), test.instruction, test.expected, processor.FlagString(test.flags))
+		}
+		if registerPair(p, R0) != 0xAAAA {
+			t.Errorf("got 0x%04X at R0 for 0x%X, want it unchanged", registerPair(p, R0), test.instruction)
+		}
+	}
+}
+
+func TestDestinationFormsFaults(t *testing.T) {
+	tests := []struct {
+		program  []uint8
+		expected error
+	}{
+		{program: []uint8{processor.DivideDst, R1, R2, R3}, expected: processor.ErrDivideByZero},
+		{program: []uint8{processor.AddDst, R1, R2, 0x08}, expected: processor.ErrInvalidRegister},
+		{program: []uint8{processor.AddWordDst, R0, R2, R7}, expected: processor.ErrInvalidRegister},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram(test.program)
+		stepAndCheckContinueValue(t, p, false)
+		if errs := p.Errors(); len(errs) != 1 || !errors.Is(errs[0], test.expected) {
+			t.Errorf("got errors %v for 0x%X, want %v", errs, test.program[0], test.expected)
+		}
+		if p.RegisterValue(R0) != 0 {
+			t.Errorf("got 0x%X at R0 for 0x%X, want it unchanged", p.RegisterValue(R0), test.program[0])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kinds of Fault.  Use errors.Is to check the kind of an execution error.
+var (
+	ErrInvalidRegister    = errors.New("invalid register")
+	ErrUnknownInstruction = errors.New("unknown instruction")
+	ErrStackOverflow      = errors.New("stack overflow")
+	ErrStackUnderflow     = errors.New("stack underflow")
+	ErrIPOutOfBounds      = errors.New("instruction pointer out of memory bounds")
+	ErrDivideByZero       = errors.New("divide by zero")
+	ErrInput              = errors.New("error reading input")
+	ErrWriteProtected     = errors.New("write to read-only memory")
+	ErrExecuteProtected   = errors.New("execute from no-execute memory")
+	ErrStackGuard         = errors.New("stack guard violation")
+	ErrFrameBounds        = errors.New("frame access outside the stack")
+)
+
+// Fault is an execution error caused by an instruction.
+type Fault struct {
+	Err                error   // Kind of fault, such as ErrStackOverflow
+	InstructionPointer uint16  // Address of the faulting instruction
+	Opcode             uint8   // Opcode of the faulting instruction
+	Operands           []uint8 // Raw operand bytes of the faulting instruction
+	Detail             string  // Description of the value at fault, if any
+	Cause              error   // Underlying error, such as from the input reader
+}
+
+func (f *Fault) Error() string {
+	message := f.Err.Error()
+	if f.Detail != "" {
+		message += " " + f.Detail
+	}
+	if f.Cause != nil {
+		message += ": " + f.Cause.Error()
+	}
+	return fmt.Sprintf("%s at position 0x%X", message, f.InstructionPointer)
+}
+
+// Is reports whether target is the kind of fault.
+func (f *Fault) Is(target error) bool {
+	return target == f.Err
+}
+
+// Unwrap returns the underlying error, so errors.Is and errors.As also
+// match it.
+func (f *Fault) Unwrap() error {
+	return f.Cause
+}
+
+// fault records a Fault for the executing instruction.
+func (p *Processor) fault(err error, detail string, cause error) {
+	f := &Fault{
+		Err:                err,
+		InstructionPointer: p.instructionStart,
+		Opcode:             p.memory.Read(p.instructionStart),
+		Detail:             detail,
+		Cause:              cause,
+	}
+	if info, found := LookupOpcode(f.Opcode); found {
+		for i := uint16(1); i < info.Size() && uint32(f.InstructionPointer)+uint32(i) <= 0xFFFF; i++ {
+			f.Operands = append(f.Operands, p.memory.Read(f.InstructionPointer+i))
+		}
+	}
+	p.errors = append(p.errors, f)
+	p.faulted = true
+}
+
+// exitStatuses maps execution errors to exit statuses.
+var exitStatuses = []struct {
+	err    error
+	status int
+}{
+	{ErrInvalidRegister, 10},
+	{ErrUnknownInstruction, 11},
+	{ErrStackOverflow, 12},
+	{ErrStackUnderflow, 13},
+	{ErrIPOutOfBounds, 14},
+	{ErrDivideByZero, 15},
+	{ErrInput, 16},
+	{ErrWriteProtected, 17},
+	{ErrExecuteProtected, 18},
+	{ErrStackGuard, 19},
+	{ErrStepLimit, 20},
+	{ErrOutOfFuel, 21},
+	{context.DeadlineExceeded, 22},
+	{ErrFrameBounds, 23},
+}
+
+// ExitStatus returns the exit status gebvm uses for the first execution
+// error.  Other errors have status 1.
+func ExitStatus(errs []error) int {
+	if len(errs) == 0 {
+		return 1
+	}
+	for _, e := range exitStatuses {
+		if errors.Is(errs[0], e.err) {
+			return e.status
+		}
+	}
+	return 1
+}
@@ -0,0 +1,137 @@
+package processor_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		program  []uint8
+		steps    int
+		kind     error
+		ip       uint16
+		operands []uint8
+		message  string
+	}{
+		{
+			name:     "invalid register",
+			program:  []uint8{processor.Noop, processor.MoveLitReg, 0x42, 9},
+			steps:    2,
+			kind:     processor.ErrInvalidRegister,
+			ip:       0x0001,
+			operands: []uint8{0x42, 9},
+			message:  "invalid register R9 at position 0x1",
+		},
+		{
+			name:    "unknown instruction",
+			program: []uint8{0x0F},
+			steps:   1,
+			kind:    processor.ErrUnknownInstruction,
+			message: "unknown instruction 0xF at position 0x0",
+		},
+		{
+			name:     "divide by zero",
+			program:  []uint8{processor.Divide, R1, R2},
+			steps:    1,
+			kind:     processor.ErrDivideByZero,
+			operands: []uint8{R1, R2},
+			message:  "divide by zero at position 0x0",
+		},
+		{
+			name:     "stack underflow",
+			program:  []uint8{processor.StackPop, R1},
+			steps:    1,
+			kind:     processor.ErrStackUnderflow,
+			operands: []uint8{R1},
+			message:  "stack underflow at position 0x0",
+		},
+		{
+			name:     "IP out of bounds",
+			program:  []uint8{processor.Jump, 0xFF, 0xFE},
+			steps:    2,
+			kind:     processor.ErrIPOutOfBounds,
+			ip:       0xFFFE,
+			operands: []uint8{0x00},
+			message:  "instruction pointer out of memory bounds at position 0xFFFE",
+		},
+	}
+
+	for _, test := range tests {
+		p, m := newTestProcessorWithPogram(test.program)
+		m.Write(0xFFFE, processor.StackPushLit)
+		for i := 0; i < test.steps; i++ {
+			p.Step()
+		}
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%s: got no errors, want a fault", test.name)
+			continue
+		}
+		var fault *processor.Fault
+		if !errors.As(errs[0], &fault) || !errors.Is(errs[0], test.kind) {
+			t.Errorf("%s: got %v, want a Fault for %v", test.name, errs[0], test.kind)
+			continue
+		}
+		if fault.InstructionPointer != test.ip || fault.Opcode != m.Read(test.ip) ||
+			!bytes.Equal(fault.Operands, test.operands) {
+			t.Errorf("%s: got fault at 0x%04X with opcode 0x%02X and operands % X, want 0x%04X, 0x%02X and % X",
+				test.name, fault.InstructionPointer, fault.Opcode, fault.Operands,
+				test.ip, m.Read(test.ip), test.operands)
+		}
+		if fault.Error() != test.message {
+			t.Errorf("%s: got %q, want %q", test.name, fault.Error(), test.message)
+		}
+	}
+}
+
+func TestStackOverflowFault(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{processor.StackPushLit, 0x00, processor.Jump, 0x00, 0x00})
+	for len(p.Errors()) == 0 {
+		p.Step()
+	}
+	if !errors.Is(p.Errors()[0], processor.ErrStackOverflow) {
+		t.Errorf("got %v, want ErrStackOverflow", p.Errors()[0])
+	}
+}
+
+func TestInputFault(t *testing.T) {
+	m := memory.New()
+	m.LoadProgram([]uint8{processor.ReadInput, R1})
+	p := processor.New(
+		m,
+		bufio.NewReader(strings.NewReader("")),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+	)
+	p.Step()
+	errs := p.Errors()
+	if len(errs) == 0 || !errors.Is(errs[0], processor.ErrInput) || !errors.Is(errs[0], io.EOF) {
+		t.Fatalf("got %v, want ErrInput caused by io.EOF", errs)
+	}
+	if errs[0].Error() != "error reading input: EOF at position 0x0" {
+		t.Errorf("got %q", errs[0])
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{processor.Divide, R2, R3})
+	if status := p.Run(); status == 0 {
+		t.Fatalf("got status 0, want an error")
+	}
+	if status := processor.ExitStatus(p.Errors()); status != 15 {
+		t.Errorf("got exit status %d, want 15", status)
+	}
+	if status := processor.ExitStatus([]error{errors.New("other")}); status != 1 {
+		t.Errorf("got exit status %d, want 1", status)
+	}
+}
@@ -0,0 +1,63 @@
+package processor
+
+const (
+	FlagZero     uint8 = 1 << iota // Result is zero
+	FlagCarry                      // Unsigned carry out of, or borrow into, the result
+	FlagOverflow                   // Signed (two's complement) overflow
+	FlagNegative                   // Bit 7 of the result is set, or bit 15 of a 16-bit result
+)
+
+func (p *Processor) Flags() uint8 {
+	return p.flags
+}
+
+// FlagString returns flags as "ZCVN", with a dash for each clear flag.
+func FlagString(flags uint8) string {
+	out := []byte("----")
+	for i, c := range []byte("ZCVN") {
+		if flags&(1<<uint(i)) != 0 {
+			out[i] = c
+		}
+	}
+	return string(out)
+}
+
+func (p *Processor) flagSet(flag uint8) bool {
+	return p.flags&flag != 0
+}
+
+// setFlags sets zero and negative from the result of an instruction,
+// and sets carry and overflow to the given values.
+func (p *Processor) setFlags(result uint8, carry, overflow bool) {
+	p.flags = 0
+	if result == 0 {
+		p.flags |= FlagZero
+	}
+	if result&0x80 != 0 {
+		p.flags |= FlagNegative
+	}
+	if carry {
+		p.flags |= FlagCarry
+	}
+	if overflow {
+		p.flags |= FlagOverflow
+	}
+}
+
+// setWordFlags sets the flags like setFlags for a 16-bit result, where
+// negative is bit 15.
+func (p *Processor) setWordFlags(result uint16, carry, overflow bool) {
+	p.flags = 0
+	if result == 0 {
+		p.flags |= FlagZero
+	}
+	if result&0x8000 != 0 {
+		p.flags |= FlagNegative
+	}
+	if carry {
+		p.flags |= FlagCarry
+	}
+	if overflow {
+		p.flags |= FlagOverflow
+	}
+}
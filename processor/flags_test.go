@@ -0,0 +1,139 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+const (
+	Z = processor.FlagZero
+	C = processor.FlagCarry
+	V = processor.FlagOverflow
+	N = processor.FlagNegative
+)
+
+func TestFlagString(t *testing.T) {
+	tests := []struct {
+		flags    uint8
+		expected string
+	}{
+		{flags: 0, expected: "----"},
+		{flags: Z | C, expected: "ZC--"},
+		{flags: V | N, expected: "--VN"},
+	}
+	for _, test := range tests {
+		if processor.FlagString(test.flags) != test.expected {
+			t.Errorf("got %s, want %s", processor.FlagString(test.flags), test.expected)
+		}
+	}
+}
+
+func TestArithmeticFlags(t *testing.T) {
+	tests := []struct {
+		instruction           uint8
+		inputLeft, inputRight uint8
+		expected              uint8
+	}{
+		{instruction: processor.Add, inputLeft: 20, inputRight: 22, expected: 0},
+		{instruction: processor.Add, inputLeft: 0xFF, inputRight: 0x01, expected: Z | C},
+		{instruction: processor.Add, inputLeft: 0x7F, inputRight: 0x01, expected: V | N},
+		{instruction: processor.Add, inputLeft: 0x80, inputRight: 0x80, expected: Z | C | V},
+		{instruction: processor.Subtract, inputLeft: 5, inputRight: 5, expected: Z},
+		{instruction: processor.Subtract, inputLeft: 5, inputRight: 6, expected: C | N},
+		{instruction: processor.Subtract, inputLeft: 0x80, inputRight: 0x01, expected: V},
+		{instruction: processor.Multiply, inputLeft: 16, inputRight: 16, expected: Z | C | V},
+		{instruction: processor.Multiply, inputLeft: 16, inputRight: 8, expected: N},
+		{instruction: processor.Divide, inputLeft: 1, inputRight: 2, expected: Z},
+		{instruction: processor.LogicalAnd, inputLeft: 0xF0, inputRight: 0x0F, expected: Z},
+		{instruction: processor.LogicalOr, inputLeft: 0xF0, inputRight: 0x0F, expected: N},
+		{instruction: processor.LogicalXor, inputLeft: 0xFF, inputRight: 0xFF, expected: Z},
+		{instruction: processor.LogicalBitClear, inputLeft: 0xFF, inputRight: 0x0F, expected: N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.inputLeft, R1,
+			processor.MoveLitReg, test.inputRight, R2,
+			test.instruction, R1, R2,
+		})
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.Flags() != test.expected {
+			t.Errorf("got %s for 0x%X with 0x%X and 0x%X, want %s", processor.FlagString(p.Flags()),
+				test.instruction, test.inputLeft, test.inputRight, processor.FlagString(test.expected))
+		}
+	}
+}
+
+func TestShiftFlags(t *testing.T) {
+	tests := []struct {
+		instruction     uint8
+		input, distance uint8
+		expected        uint8
+	}{
+		{instruction: processor.LogicalShiftLeft, input: 0x81, distance: 1, expected: C},
+		{instruction: processor.LogicalShiftLeft, input: 0x40, distance: 1, expected: N},
+		{instruction: processor.LogicalShiftLeft, input: 0x01, distance: 8, expected: Z | C},
+		{instruction: processor.LogicalShiftLeft, input: 0xFF, distance: 9, expected: Z},
+		{instruction: processor.LogicalShiftRight, input: 0x03, distance: 1, expected: C},
+		{instruction: processor.LogicalShiftRight, input: 0x80, distance: 8, expected: Z | C},
+		{instruction: processor.LogicalShiftRight, input: 0x80, distance: 0, expected: N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.input, R1,
+			test.instruction, R1, test.distance,
+		})
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.Flags() != test.expected {
+			t.Errorf("got %s for 0x%X with 0x%X by %d, want %s", processor.FlagString(p.Flags()),
+				test.instruction, test.input, test.distance, processor.FlagString(test.expected))
+		}
+	}
+}
+
+func TestIncDecFlags(t *testing.T) {
+	tests := []struct {
+		instruction uint8
+		input       uint8
+		expected    uint8
+	}{
+		{instruction: processor.Inc, input: 0xFF, expected: Z | C}, // Carry left set by ADD
+		{instruction: processor.Inc, input: 0x7F, expected: C | V | N},
+		{instruction: processor.Dec, input: 0x01, expected: Z | C},
+		{instruction: processor.Dec, input: 0x80, expected: C | V},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, 0xFF, R1,
+			processor.Add, R1, R1, // Sets carry
+			processor.MoveLitReg, test.input, R1,
+			test.instruction, R1,
+		})
+		p.Step()
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.Flags() != test.expected {
+			t.Errorf("got %s for 0x%X with 0x%X, want %s", processor.FlagString(p.Flags()),
+				test.instruction, test.input, processor.FlagString(test.expected))
+		}
+	}
+}
+
+func TestMovesDoNotChangeFlags(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{
+		processor.Add, R0, R0, // Sets zero
+		processor.MoveLitReg, 0x01, R0,
+	})
+	p.Step()
+	stepAndCheckContinueValue(t, p, true)
+	if p.Flags() != Z {
+		t.Errorf("got %s, want Z---", processor.FlagString(p.Flags()))
+	}
+}
@@ -0,0 +1,58 @@
+package processor
+
+import "fmt"
+
+// The frame pointer is the address of the first value pushed in the
+// current call frame.  CLL saves 9 bytes just below it, and the values
+// the caller pushed before the call, such as arguments, are below those.
+// Frame instructions take a signed offset from the frame pointer, so
+// locals have offsets from 0 and arguments have offsets from -10.
+
+// FramePointer returns the address of the current call frame.
+func (p *Processor) FramePointer() uint16 {
+	return p.stackPointer - uint16(p.stackSize)
+}
+
+// frameAddress returns the address at offset from the frame pointer.  It
+// faults if the address is not on the stack.
+func (p *Processor) frameAddress(offset uint8) (uint16, bool) {
+	address := int(p.FramePointer()) + int(int8(offset))
+	if address < int(StackStart) || address >= int(p.stackPointer) {
+		p.fault(ErrFrameBounds, fmt.Sprintf("FP%+d", int8(offset)), nil)
+		return 0, false
+	}
+	return uint16(address), true
+}
+
+func (p *Processor) executeLoadFrame() bool {
+	offset := p.fetchInstruction()
+	register := p.fetchInstruction()
+	address, ok := p.frameAddress(offset)
+	if !ok || !p.canAccess(address, false, true) {
+		return false
+	}
+	p.SetRegisterValue(register, p.memory.Read(address))
+	return true
+}
+
+func (p *Processor) executeStoreFrame() bool {
+	register := p.fetchInstruction()
+	offset := p.fetchInstruction()
+	address, ok := p.frameAddress(offset)
+	if !ok || !p.canAccess(address, true, true) {
+		return false
+	}
+	p.storeMemory(address, p.RegisterValue(register))
+	return true
+}
+
+func (p *Processor) executeDrop() bool {
+	count := p.fetchInstruction()
+	if count > p.stackSize {
+		p.fault(ErrStackUnderflow, "", nil)
+		return false
+	}
+	p.stackPointer -= uint16(count)
+	p.stackSize -= count
+	return true
+}
@@ -0,0 +1,70 @@
+package processor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestFrameCall(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{
+		processor.StackPushLit, 0x11, // 0x0000 First argument
+		processor.StackPushLit, 0x22, // 0x0002 Second argument
+		processor.Call, 0x00, 0x0A, // 0x0004
+		processor.Drop, 2, // 0x0007
+		processor.Halt,               // 0x0009
+		processor.StackPushLit, 0x00, // 0x000A Local
+		processor.LoadFrame, 0xF6, R2, // 0x000C Second argument at -10
+		processor.LoadFrame, 0xF5, R3, // 0x000F First argument at -11
+		processor.Add, R2, R3, // 0x0012
+		processor.StoreFrame, R0, 0x00, // 0x0015
+		processor.LoadFrame, 0x00, R1, // 0x0018
+		processor.Return, // 0x001B
+	})
+	for i := 0; i < 3; i++ {
+		p.Step()
+	}
+	if p.FramePointer() != p.StackPointer() || p.FramePointer() != processor.StackStart+11 {
+		t.Errorf("got frame pointer 0x%04X in the call, want 0x%04X", p.FramePointer(), processor.StackStart+11)
+	}
+	p.Step()
+	if p.FramePointer() != processor.StackStart+11 {
+		t.Errorf("got frame pointer 0x%04X after a push, want 0x%04X", p.FramePointer(), processor.StackStart+11)
+	}
+	for p.Step() {
+	}
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("got errors %v, want none", errs)
+	}
+	if p.RegisterValue(R0) != 0x33 || p.RegisterValue(R1) != 0x33 {
+		t.Errorf("got 0x%X and 0x%X at R0 and R1, want 0x33", p.RegisterValue(R0), p.RegisterValue(R1))
+	}
+	if p.RegisterValue(R2) != 0 || p.RegisterValue(R3) != 0 {
+		t.Errorf("got 0x%X and 0x%X at R2 and R3, want them restored", p.RegisterValue(R2), p.RegisterValue(R3))
+	}
+	if p.StackPointer() != processor.StackStart || p.StackSize() != 0 || p.FramePointer() != processor.StackStart {
+		t.Errorf("got SP 0x%04X and stack size %d, want an empty stack", p.StackPointer(), p.StackSize())
+	}
+}
+
+func TestFrameFaults(t *testing.T) {
+	tests := []struct {
+		program  []uint8
+		expected error
+	}{
+		{program: []uint8{processor.LoadFrame, 0x00, R1}, expected: processor.ErrFrameBounds},
+		{program: []uint8{processor.StackPushLit, 0x2A, processor.LoadFrame, 0xFF, R1}, expected: processor.ErrFrameBounds},
+		{program: []uint8{processor.StackPushLit, 0x2A, processor.StoreFrame, R1, 0x01}, expected: processor.ErrFrameBounds},
+		{program: []uint8{processor.StackPushLit, 0x2A, processor.Drop, 2}, expected: processor.ErrStackUnderflow},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram(test.program)
+		for p.Step() {
+		}
+		if errs := p.Errors(); len(errs) != 1 || !errors.Is(errs[0], test.expected) {
+			t.Errorf("got errors %v for % X, want %v", errs, test.program, test.expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestImmediateInstructions(t *testing.T) {
+	tests := []struct {
+		instruction uint8
+		input       uint8
+		literal     uint8
+		expected    uint8 // R0, which starts at 0xAA
+		flags       uint8
+	}{
+		{instruction: processor.AddLit, input: 20, literal: 22, expected: 42, flags: 0},
+		{instruction: processor.AddLit, input: 0xFF, literal: 0x01, expected: 0x00, flags: Z | C},
+		{instruction: processor.AddLit, input: 0x7F, literal: 0x01, expected: 0x80, flags: V | N},
+		{instruction: processor.SubtractLit, input: 5, literal: 6, expected: 0xFF, flags: C | N},
+		{instruction: processor.SubtractLit, input: 0x80, literal: 0x01, expected: 0x7F, flags: V},
+		{instruction: processor.CompareLit, input: 5, literal: 5, expected: 0xAA, flags: Z},
+		{instruction: processor.CompareLit, input: 4, literal: 5, expected: 0xAA, flags: C | N},
+		{instruction: processor.LogicalAndLit, input: 0xF0, literal: 0x3C, expected: 0x30, flags: 0},
+		{instruction: processor.LogicalAndLit, input: 0xF0, literal: 0x0F, expected: 0x00, flags: Z},
+		{instruction: processor.LogicalOrLit, input: 0xF0, literal: 0x0F, expected: 0xFF, flags: N},
+		{instruction: processor.LogicalXorLit, input: 0xFF, literal: 0x0F, expected: 0xF0, flags: N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, 0xAA, R0,
+			processor.MoveLitReg, test.input, R3,
+			test.instruction, R3, test.literal,
+		})
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R0) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%X and %s for 0x%X with 0x%X and 0x%X, want 0x%X and %s",
+				p.RegisterValue(R0), processor.FlagString(p.Flags()), test.instruction,
+				test.input, test.literal, test.expected, processor.FlagString(test.flags))
+		}
+		if p.RegisterValue(R3) != test.input {
+			t.Errorf("got 0x%X at R3 for 0x%X, want it unchanged", p.RegisterValue(R3), test.instruction)
+		}
+	}
+}
+
+func TestShiftByRegister(t *testing.T) {
+	tests := []struct {
+		instruction uint8
+		input       uint8
+		distance    uint8
+		expected    uint8
+		flags       uint8
+	}{
+		{instruction: processor.ShiftLeftReg, input: 0x55, distance: 1, expected: 0xAA, flags: N},
+		{instruction: processor.ShiftLeftReg, input: 0xD6, distance: 4, expected: 0x60, flags: C},
+		{instruction: processor.ShiftLeftReg, input: 0xCE, distance: 9, expected: 0x00, flags: Z},
+		{instruction: processor.ShiftRightReg, input: 0x55, distance: 1, expected: 0x2A, flags: C},
+		{instruction: processor.ShiftRightReg, input: 0xD6, distance: 4, expected: 0x0D, flags: 0},
+		{instruction: processor.ArithShiftReg, input: 0xD6, distance: 2, expected: 0xF5, flags: C | N},
+		{instruction: processor.ArithShiftReg, input: 0x80, distance: 0xFF, expected: 0xFF, flags: C | N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.input, R1,
+			processor.MoveLitReg, test.distance, R6,
+			test.instruction, R1, R6,
+		})
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R0) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%X and %s for 0x%X with 0x%X by %d, want 0x%X and %s",
+				p.RegisterValue(R0), processor.FlagString(p.Flags()), test.instruction,
+				test.input, test.distance, test.expected, processor.FlagString(test.flags))
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package processor
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -12,66 +11,234 @@ const (
 	MoveLitMem        uint8 = 0x03 // MLM
 	MoveRegMem        uint8 = 0x04 // MRM
 	MoveMemReg        uint8 = 0x05 // MMR
+	MoveLitWord       uint8 = 0x06 // MLW
+	LoadOffset        uint8 = 0x07 // LDO
+	LoadIndexed       uint8 = 0x08 // LDX
+	StoreOffset       uint8 = 0x09 // STO
+	StoreIndexed      uint8 = 0x0A // STX
 	LogicalAnd        uint8 = 0x20 // LND
 	LogicalOr         uint8 = 0x21 // LOR
 	LogicalXor        uint8 = 0x22 // LXR
 	LogicalBitClear   uint8 = 0x23 // LBC
 	LogicalShiftLeft  uint8 = 0x24 // LSL
 	LogicalShiftRight uint8 = 0x25 // LSR
+	ArithShiftRight   uint8 = 0x26 // ASR
+	LogicalAndLit     uint8 = 0x27 // ANI
+	LogicalOrLit      uint8 = 0x28 // ORI
+	LogicalXorLit     uint8 = 0x29 // XRI
+	ShiftLeftReg      uint8 = 0x2A // LLR
+	ShiftRightReg     uint8 = 0x2B // LRR
+	ArithShiftReg     uint8 = 0x2C // ARR
 	Inc               uint8 = 0x40 // INC
 	Dec               uint8 = 0x41 // DEC
 	Add               uint8 = 0x42 // ADD
 	Subtract          uint8 = 0x43 // SUB
 	Multiply          uint8 = 0x44 // MUL
 	Divide            uint8 = 0x45 // DIV
+	Compare           uint8 = 0x46 // CMP
+	Negate            uint8 = 0x47 // NEG
+	SignedMultiply    uint8 = 0x48 // SML
+	SignedDivide      uint8 = 0x49 // SDV
+	SignedRemainder   uint8 = 0x4A // SRM
+	Modulo            uint8 = 0x4B // MOD
+	AddLit            uint8 = 0x4C // ADI
+	SubtractLit       uint8 = 0x4D // SBI
+	CompareLit        uint8 = 0x4E // CPI
+	IncWord           uint8 = 0x50 // INW
+	DecWord           uint8 = 0x51 // DCW
+	AddWord           uint8 = 0x52 // ADW
+	SubtractWord      uint8 = 0x53 // SBW
+	CompareWord       uint8 = 0x54 // CPW
+	SignExtend        uint8 = 0x55 // SXT
 	Jump              uint8 = 0x60 // JMP
 	JumpEqual         uint8 = 0x61 // JEQ
 	JumpNotEqual      uint8 = 0x62 // JNE
+	JumpLessThan      uint8 = 0x63 // JLT
+	JumpGreaterThan   uint8 = 0x64 // JGT
+	JumpLessEqual     uint8 = 0x65 // JLE
+	JumpGreaterEqual  uint8 = 0x66 // JGE
+	JumpLower         uint8 = 0x67 // JLO
+	JumpHigher        uint8 = 0x68 // JHI
+	JumpLowerSame     uint8 = 0x69 // JLS
+	JumpHigherSame    uint8 = 0x6A // JHS
+	JumpCarrySet      uint8 = 0x6B // JCS
+	JumpCarryClear    uint8 = 0x6C // JCC
+	JumpZeroSet       uint8 = 0x6D // JZS
+	JumpZeroClear     uint8 = 0x6E // JZC
 	StackPushLit      uint8 = 0x80 // SPL
 	StackPushReg      uint8 = 0x81 // SPR
 	StackPop          uint8 = 0x82 // STP
 	Call              uint8 = 0x83 // CLL
 	Return            uint8 = 0x84 // RET
+	ReturnInterrupt   uint8 = 0x85 // RTI
+	LoadFrame         uint8 = 0x86 // LDF
+	StoreFrame        uint8 = 0x87 // STF
+	Drop              uint8 = 0x88 // DRP
+
+	// ALU instructions naming a destination register as their last operand
+	LogicalAndDst        uint8 = 0xA0 // LND
+	LogicalOrDst         uint8 = 0xA1 // LOR
+	LogicalXorDst        uint8 = 0xA2 // LXR
+	LogicalBitClearDst   uint8 = 0xA3 // LBC
+	LogicalShiftLeftDst  uint8 = 0xA4 // LSL
+	LogicalShiftRightDst uint8 = 0xA5 // LSR
+	ArithShiftRightDst   uint8 = 0xA6 // ASR
+	LogicalAndLitDst     uint8 = 0xA7 // ANI
+	LogicalOrLitDst      uint8 = 0xA8 // ORI
+	LogicalXorLitDst     uint8 = 0xA9 // XRI
+	ShiftLeftRegDst      uint8 = 0xAA // LLR
+	ShiftRightRegDst     uint8 = 0xAB // LRR
+	ArithShiftRegDst     uint8 = 0xAC // ARR
+	AddDst               uint8 = 0xAD // ADD
+	SubtractDst          uint8 = 0xAE // SUB
+	MultiplyDst          uint8 = 0xAF // MUL
+	DivideDst            uint8 = 0xB0 // DIV
+	SignedMultiplyDst    uint8 = 0xB1 // SML
+	SignedDivideDst      uint8 = 0xB2 // SDV
+	SignedRemainderDst   uint8 = 0xB3 // SRM
+	ModuloDst            uint8 = 0xB4 // MOD
+	AddLitDst            uint8 = 0xB5 // ADI
+	SubtractLitDst       uint8 = 0xB6 // SBI
+	AddWordDst           uint8 = 0xB7 // ADW
+	SubtractWordDst      uint8 = 0xB8 // SBW
+
 	Print             uint8 = 0xE0 // PNT
 	ReadInput         uint8 = 0xE1 // RIN
+	EnableInterrupts  uint8 = 0xE2 // EI
+	DisableInterrupts uint8 = 0xE3 // DI
 	Halt              uint8 = 0xFF // HLT
 )
 
 // Returns bool indicating if the program should continue running
 type instruction func(*Processor) bool
 
-var instructions = map[uint8]instruction{
+// instructions is indexed by opcode.  Unknown opcodes are nil.
+var instructions = [0xFF + 1]instruction{
 	Noop:              (*Processor).executeNoop,
 	MoveLitReg:        (*Processor).executeMoveLitReg,
 	MoveRegReg:        (*Processor).executeMoveRegReg,
 	MoveLitMem:        (*Processor).executeMoveLitMem,
 	MoveRegMem:        (*Processor).executeMoveRegMem,
 	MoveMemReg:        (*Processor).executeMoveMemReg,
+	MoveLitWord:       (*Processor).executeMoveLitWord,
+	LoadOffset:        (*Processor).executeLoadOffset,
+	LoadIndexed:       (*Processor).executeLoadIndexed,
+	StoreOffset:       (*Processor).executeStoreOffset,
+	StoreIndexed:      (*Processor).executeStoreIndexed,
 	LogicalAnd:        (*Processor).executeLogicalAnd,
 	LogicalOr:         (*Processor).executeLogicalOr,
 	LogicalXor:        (*Processor).executeLogicalXor,
 	LogicalBitClear:   (*Processor).executeLogicalBitClear,
 	LogicalShiftLeft:  (*Processor).executeLogicalShiftLeft,
 	LogicalShiftRight: (*Processor).executeLogicalShiftRight,
+	ArithShiftRight:   (*Processor).executeArithShiftRight,
+	LogicalAndLit:     (*Processor).executeLogicalAndLit,
+	LogicalOrLit:      (*Processor).executeLogicalOrLit,
+	LogicalXorLit:     (*Processor).executeLogicalXorLit,
+	ShiftLeftReg:      (*Processor).executeShiftLeftReg,
+	ShiftRightReg:     (*Processor).executeShiftRightReg,
+	ArithShiftReg:     (*Processor).executeArithShiftReg,
 	Inc:               (*Processor).executeInc,
 	Dec:               (*Processor).executeDec,
 	Add:               (*Processor).executeAdd,
 	Subtract:          (*Processor).executeSubtract,
 	Multiply:          (*Processor).executeMultiply,
 	Divide:            (*Processor).executeDivide,
+	Compare:           (*Processor).executeCompare,
+	Negate:            (*Processor).executeNegate,
+	SignedMultiply:    (*Processor).executeSignedMultiply,
+	SignedDivide:      (*Processor).executeSignedDivide,
+	SignedRemainder:   (*Processor).executeSignedRemainder,
+	Modulo:            (*Processor).executeModulo,
+	AddLit:            (*Processor).executeAddLit,
+	SubtractLit:       (*Processor).executeSubtractLit,
+	CompareLit:        (*Processor).executeCompareLit,
+	IncWord:           (*Processor).executeIncWord,
+	DecWord:           (*Processor).executeDecWord,
+	AddWord:           (*Processor).executeAddWord,
+	SubtractWord:      (*Processor).executeSubtractWord,
+	CompareWord:       (*Processor).executeCompareWord,
+	SignExtend:        (*Processor).executeSignExtend,
 	Jump:              (*Processor).executeJump,
 	JumpEqual:         (*Processor).executeJumpEqual,
 	JumpNotEqual:      (*Processor).executeJumpNotEqual,
+	JumpLessThan:      (*Processor).executeJumpLessThan,
+	JumpGreaterThan:   (*Processor).executeJumpGreaterThan,
+	JumpLessEqual:     (*Processor).executeJumpLessEqual,
+	JumpGreaterEqual:  (*Processor).executeJumpGreaterEqual,
+	JumpLower:         (*Processor).executeJumpLower,
+	JumpHigher:        (*Processor).executeJumpHigher,
+	JumpLowerSame:     (*Processor).executeJumpLowerSame,
+	JumpHigherSame:    (*Processor).executeJumpHigherSame,
+	JumpCarrySet:      (*Processor).executeJumpCarrySet,
+	JumpCarryClear:    (*Processor).executeJumpCarryClear,
+	JumpZeroSet:       (*Processor).executeJumpZeroSet,
+	JumpZeroClear:     (*Processor).executeJumpZeroClear,
 	StackPushLit:      (*Processor).executeStackPushLit,
 	StackPushReg:      (*Processor).executeStackPushReg,
 	StackPop:          (*Processor).executeStackPop,
 	Call:              (*Processor).executeCall,
 	Return:            (*Processor).executeReturn,
+	ReturnInterrupt:   (*Processor).executeReturnInterrupt,
+	LoadFrame:         (*Processor).executeLoadFrame,
+	StoreFrame:        (*Processor).executeStoreFrame,
+	Drop:              (*Processor).executeDrop,
+
+	LogicalAndDst:        withDestination((*Processor).executeLogicalAnd),
+	LogicalOrDst:         withDestination((*Processor).executeLogicalOr),
+	LogicalXorDst:        withDestination((*Processor).executeLogicalXor),
+	LogicalBitClearDst:   withDestination((*Processor).executeLogicalBitClear),
+	LogicalShiftLeftDst:  withDestination((*Processor).executeLogicalShiftLeft),
+	LogicalShiftRightDst: withDestination((*Processor).executeLogicalShiftRight),
+	ArithShiftRightDst:   withDestination((*Processor).executeArithShiftRight),
+	LogicalAndLitDst:     withDestination((*Processor).executeLogicalAndLit),
+	LogicalOrLitDst:      withDestination((*Processor).executeLogicalOrLit),
+	LogicalXorLitDst:     withDestination((*Processor).executeLogicalXorLit),
+	ShiftLeftRegDst:      withDestination((*Processor).executeShiftLeftReg),
+	ShiftRightRegDst:     withDestination((*Processor).executeShiftRightReg),
+	ArithShiftRegDst:     withDestination((*Processor).executeArithShiftReg),
+	AddDst:               withDestination((*Processor).executeAdd),
+	SubtractDst:          withDestination((*Processor).executeSubtract),
+	MultiplyDst:          withDestination((*Processor).executeMultiply),
+	DivideDst:            withDestination((*Processor).executeDivide),
+	SignedMultiplyDst:    withDestination((*Processor).executeSignedMultiply),
+	SignedDivideDst:      withDestination((*Processor).executeSignedDivide),
+	SignedRemainderDst:   withDestination((*Processor).executeSignedRemainder),
+	ModuloDst:            withDestination((*Processor).executeModulo),
+	AddLitDst:            withDestination((*Processor).executeAddLit),
+	SubtractLitDst:       withDestination((*Processor).executeSubtractLit),
+	AddWordDst:           withDestination((*Processor).executeAddWord),
+	SubtractWordDst:      withDestination((*Processor).executeSubtractWord),
+
 	Print:             (*Processor).executePrint,
 	ReadInput:         (*Processor).executeReadInput,
+	EnableInterrupts:  (*Processor).executeEnableInterrupts,
+	DisableInterrupts: (*Processor).executeDisableInterrupts,
 	Halt:              (*Processor).executeHalt,
 }
 
+// withDestination returns the form of an ALU instruction that names a
+// destination register in an extra last operand, instead of storing its
+// result in R0.
+func withDestination(execute instruction) instruction {
+	return func(p *Processor) bool {
+		p.namedDestination = true
+		continueRunning := execute(p)
+		p.namedDestination = false
+		return continueRunning
+	}
+}
+
+// storeResult stores the result of an ALU instruction in R0, or in the
+// destination register of a form that names one.
+func (p *Processor) storeResult(value uint8) {
+	register := uint8(0)
+	if p.namedDestination {
+		register = p.fetchInstruction()
+	}
+	p.SetRegisterValue(register, value)
+}
+
 func (p *Processor) executeNoop() bool {
 	return true
 }
@@ -83,14 +250,14 @@ func (p *Processor) executeNoop() bool {
 func (p *Processor) executeMoveLitReg() bool {
 	literal := p.fetchInstruction()
 	register := p.fetchInstruction()
-	p.setRegisterValue(register, literal)
+	p.SetRegisterValue(register, literal)
 	return true
 }
 
 func (p *Processor) executeMoveRegReg() bool {
 	srcRegister := p.fetchInstruction()
 	dstRegister := p.fetchInstruction()
-	p.setRegisterValue(dstRegister, p.RegisterValue(srcRegister))
+	p.SetRegisterValue(dstRegister, p.RegisterValue(srcRegister))
 	return true
 }
 
@@ -98,7 +265,7 @@ func (p *Processor) executeMoveLitMem() bool {
 	literal := p.fetchInstruction()
 	register := p.fetchInstruction()
 	address := p.registerPointerValue(register)
-	p.memory.Write(address, literal)
+	p.writeMemory(address, literal)
 	return true
 }
 
@@ -106,7 +273,7 @@ func (p *Processor) executeMoveRegMem() bool {
 	srcRegister := p.fetchInstruction()
 	addressRegister := p.fetchInstruction()
 	address := p.registerPointerValue(addressRegister)
-	p.memory.Write(address, p.RegisterValue(srcRegister))
+	p.writeMemory(address, p.RegisterValue(srcRegister))
 	return true
 }
 
@@ -114,7 +281,46 @@ func (p *Processor) executeMoveMemReg() bool {
 	addressRegister := p.fetchInstruction()
 	address := p.registerPointerValue(addressRegister)
 	dstRegister := p.fetchInstruction()
-	p.setRegisterValue(dstRegister, p.memory.Read(address))
+	p.SetRegisterValue(dstRegister, p.readMemory(address))
+	return true
+}
+
+// The offset and index of the load and store instructions are unsigned,
+// and the address wraps around from 0xFFFF to 0x0000.
+
+func (p *Processor) executeLoadOffset() bool {
+	addressRegister := p.fetchInstruction()
+	offset := p.fetchInstruction()
+	dstRegister := p.fetchInstruction()
+	address := p.registerPointerValue(addressRegister) + uint16(offset)
+	p.SetRegisterValue(dstRegister, p.readMemory(address))
+	return true
+}
+
+func (p *Processor) executeLoadIndexed() bool {
+	addressRegister := p.fetchInstruction()
+	indexRegister := p.fetchInstruction()
+	dstRegister := p.fetchInstruction()
+	address := p.registerPointerValue(addressRegister) + uint16(p.RegisterValue(indexRegister))
+	p.SetRegisterValue(dstRegister, p.readMemory(address))
+	return true
+}
+
+func (p *Processor) executeStoreOffset() bool {
+	srcRegister := p.fetchInstruction()
+	addressRegister := p.fetchInstruction()
+	offset := p.fetchInstruction()
+	address := p.registerPointerValue(addressRegister) + uint16(offset)
+	p.writeMemory(address, p.RegisterValue(srcRegister))
+	return true
+}
+
+func (p *Processor) executeStoreIndexed() bool {
+	srcRegister := p.fetchInstruction()
+	addressRegister := p.fetchInstruction()
+	indexRegister := p.fetchInstruction()
+	address := p.registerPointerValue(addressRegister) + uint16(p.RegisterValue(indexRegister))
+	p.writeMemory(address, p.RegisterValue(srcRegister))
 	return true
 }
 
@@ -122,46 +328,127 @@ func (p *Processor) executeMoveMemReg() bool {
  * LOGIC *
  *********/
 
+// logic stores the result of a logic instruction and sets the flags.
+func (p *Processor) logic(result uint8) bool {
+	p.storeResult(result)
+	p.setFlags(result, false, false)
+	return true
+}
+
 func (p *Processor) executeLogicalAnd() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	p.setRegisterValue(0, p.RegisterValue(registerLeft)&p.RegisterValue(registerRight))
-	return true
+	return p.logic(p.RegisterValue(registerLeft) & p.RegisterValue(registerRight))
 }
 
 func (p *Processor) executeLogicalOr() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	p.setRegisterValue(0, p.RegisterValue(registerLeft)|p.RegisterValue(registerRight))
-	return true
+	return p.logic(p.RegisterValue(registerLeft) | p.RegisterValue(registerRight))
 }
 
 func (p *Processor) executeLogicalXor() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	p.setRegisterValue(0, p.RegisterValue(registerLeft)^p.RegisterValue(registerRight))
-	return true
+	return p.logic(p.RegisterValue(registerLeft) ^ p.RegisterValue(registerRight))
 }
 
 func (p *Processor) executeLogicalBitClear() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	p.setRegisterValue(0, p.RegisterValue(registerLeft)&^p.RegisterValue(registerRight))
+	return p.logic(p.RegisterValue(registerLeft) &^ p.RegisterValue(registerRight))
+}
+
+func (p *Processor) executeLogicalAndLit() bool {
+	register := p.fetchInstruction()
+	literal := p.fetchInstruction()
+	return p.logic(p.RegisterValue(register) & literal)
+}
+
+func (p *Processor) executeLogicalOrLit() bool {
+	register := p.fetchInstruction()
+	literal := p.fetchInstruction()
+	return p.logic(p.RegisterValue(register) | literal)
+}
+
+func (p *Processor) executeLogicalXorLit() bool {
+	register := p.fetchInstruction()
+	literal := p.fetchInstruction()
+	return p.logic(p.RegisterValue(register) ^ literal)
+}
+
+// shiftLeft stores value shifted left by distance and sets the flags.
+func (p *Processor) shiftLeft(value, shiftDistance uint8) bool {
+	result := value << shiftDistance
+	// Carry is the last bit shifted out
+	carry := shiftDistance >= 1 && shiftDistance <= 8 && value&(1<<(8-shiftDistance)) != 0
+	p.storeResult(result)
+	p.setFlags(result, carry, false)
+	return true
+}
+
+// shiftRight stores value shifted right by distance and sets the flags.
+func (p *Processor) shiftRight(value, shiftDistance uint8) bool {
+	result := value >> shiftDistance
+	// Carry is the last bit shifted out
+	carry := shiftDistance >= 1 && shiftDistance <= 8 && value&(1<<(shiftDistance-1)) != 0
+	p.storeResult(result)
+	p.setFlags(result, carry, false)
+	return true
+}
+
+// arithShiftRight stores value shifted right by distance, keeping its
+// sign, and sets the flags.
+func (p *Processor) arithShiftRight(value, shiftDistance uint8) bool {
+	// Shifts of 8 or more fill the result with the sign bit
+	result := uint8(int8(value) >> shiftDistance)
+	lastShifted := shiftDistance
+	if lastShifted > 8 {
+		lastShifted = 8
+	}
+	carry := shiftDistance >= 1 && (int8(value)>>(lastShifted-1))&1 != 0
+	p.storeResult(result)
+	p.setFlags(result, carry, false)
 	return true
 }
 
 func (p *Processor) executeLogicalShiftLeft() bool {
 	register := p.fetchInstruction()
 	shiftDistance := p.fetchInstruction()
-	p.setRegisterValue(0, p.RegisterValue(register)<<shiftDistance)
-	return true
+	return p.shiftLeft(p.RegisterValue(register), shiftDistance)
 }
 
 func (p *Processor) executeLogicalShiftRight() bool {
 	register := p.fetchInstruction()
 	shiftDistance := p.fetchInstruction()
-	p.setRegisterValue(0, p.RegisterValue(register)>>shiftDistance)
-	return true
+	return p.shiftRight(p.RegisterValue(register), shiftDistance)
+}
+
+func (p *Processor) executeArithShiftRight() bool {
+	register := p.fetchInstruction()
+	shiftDistance := p.fetchInstruction()
+	return p.arithShiftRight(p.RegisterValue(register), shiftDistance)
+}
+
+// Shifts by a register use the value in the second register as the
+// distance
+
+func (p *Processor) executeShiftLeftReg() bool {
+	register := p.fetchInstruction()
+	distanceRegister := p.fetchInstruction()
+	return p.shiftLeft(p.RegisterValue(register), p.RegisterValue(distanceRegister))
+}
+
+func (p *Processor) executeShiftRightReg() bool {
+	register := p.fetchInstruction()
+	distanceRegister := p.fetchInstruction()
+	return p.shiftRight(p.RegisterValue(register), p.RegisterValue(distanceRegister))
+}
+
+func (p *Processor) executeArithShiftReg() bool {
+	register := p.fetchInstruction()
+	distanceRegister := p.fetchInstruction()
+	return p.arithShiftRight(p.RegisterValue(register), p.RegisterValue(distanceRegister))
 }
 
 /*********
@@ -170,37 +457,72 @@ func (p *Processor) executeLogicalShiftRight() bool {
 
 func (p *Processor) executeInc() bool {
 	register := p.fetchInstruction()
-	p.setRegisterValue(register, p.RegisterValue(register)+1)
+	value := p.RegisterValue(register)
+	p.SetRegisterValue(register, value+1)
+	// Carry is unchanged so INC can count multi-byte loops
+	p.setFlags(value+1, p.flags&FlagCarry != 0, value == 0x7F)
 	return true
 }
 
 func (p *Processor) executeDec() bool {
 	register := p.fetchInstruction()
-	p.setRegisterValue(register, p.RegisterValue(register)-1)
+	value := p.RegisterValue(register)
+	p.SetRegisterValue(register, value-1)
+	// Carry is unchanged so DEC can count multi-byte loops
+	p.setFlags(value-1, p.flags&FlagCarry != 0, value == 0x80)
 	return true
 }
 
 func (p *Processor) executeAdd() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	sum := p.RegisterValue(registerLeft) + p.RegisterValue(registerRight)
-	p.setRegisterValue(0, sum)
+	p.add(p.RegisterValue(registerLeft), p.RegisterValue(registerRight))
+	return true
+}
+
+func (p *Processor) executeAddLit() bool {
+	register := p.fetchInstruction()
+	literal := p.fetchInstruction()
+	p.add(p.RegisterValue(register), literal)
 	return true
 }
 
+// add stores left + right and sets the flags for the result.
+func (p *Processor) add(left, right uint8) {
+	sum := left + right
+	p.storeResult(sum)
+	p.setFlags(sum, uint16(left)+uint16(right) > 0xFF, (left^sum)&(right^sum)&0x80 != 0)
+}
+
 func (p *Processor) executeSubtract() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	diff := p.RegisterValue(registerLeft) - p.RegisterValue(registerRight)
-	p.setRegisterValue(0, diff)
+	diff := p.subtract(p.RegisterValue(registerLeft), p.RegisterValue(registerRight))
+	p.storeResult(diff)
 	return true
 }
 
+func (p *Processor) executeSubtractLit() bool {
+	register := p.fetchInstruction()
+	literal := p.fetchInstruction()
+	p.storeResult(p.subtract(p.RegisterValue(register), literal))
+	return true
+}
+
+// subtract returns left - right and sets the flags for the result.
+func (p *Processor) subtract(left, right uint8) uint8 {
+	diff := left - right
+	p.setFlags(diff, left < right, (left^right)&(left^diff)&0x80 != 0)
+	return diff
+}
+
 func (p *Processor) executeMultiply() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
-	product := p.RegisterValue(registerLeft) * p.RegisterValue(registerRight)
-	p.setRegisterValue(0, product)
+	wideProduct := uint16(p.RegisterValue(registerLeft)) * uint16(p.RegisterValue(registerRight))
+	product := uint8(wideProduct)
+	p.storeResult(product)
+	p.setFlags(product, wideProduct > 0xFF, wideProduct > 0xFF)
 	return true
 }
 
@@ -208,11 +530,96 @@ func (p *Processor) executeDivide() bool {
 	registerLeft := p.fetchInstruction()
 	registerRight := p.fetchInstruction()
 	if p.RegisterValue(registerRight) == 0x00 {
-		p.errors = append(p.errors, errors.New("divide by zero"))
+		p.fault(ErrDivideByZero, "", nil)
 		return false
 	}
 	quotient := p.RegisterValue(registerLeft) / p.RegisterValue(registerRight)
-	p.setRegisterValue(0, quotient)
+	p.storeResult(quotient)
+	p.setFlags(quotient, false, false)
+	return true
+}
+
+func (p *Processor) executeCompare() bool {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	p.subtract(p.RegisterValue(registerLeft), p.RegisterValue(registerRight))
+	return true
+}
+
+func (p *Processor) executeCompareLit() bool {
+	register := p.fetchInstruction()
+	literal := p.fetchInstruction()
+	p.subtract(p.RegisterValue(register), literal)
+	return true
+}
+
+// Signed instructions treat register values as two's complement numbers
+// from -128 to 127
+
+func (p *Processor) executeNegate() bool {
+	register := p.fetchInstruction()
+	// Negating -128 overflows and leaves -128
+	p.SetRegisterValue(register, p.subtract(0, p.RegisterValue(register)))
+	return true
+}
+
+func (p *Processor) executeSignedMultiply() bool {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	wideProduct := int16(int8(p.RegisterValue(registerLeft))) * int16(int8(p.RegisterValue(registerRight)))
+	product := uint8(wideProduct)
+	p.storeResult(product)
+	outOfRange := wideProduct < -128 || wideProduct > 127
+	p.setFlags(product, outOfRange, outOfRange)
+	return true
+}
+
+// signedOperands fetches the operands of a signed division, or returns
+// false after a fault if the divisor is zero.
+func (p *Processor) signedOperands() (int8, int8, bool) {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	if p.RegisterValue(registerRight) == 0x00 {
+		p.fault(ErrDivideByZero, "", nil)
+		return 0, 0, false
+	}
+	return int8(p.RegisterValue(registerLeft)), int8(p.RegisterValue(registerRight)), true
+}
+
+func (p *Processor) executeSignedDivide() bool {
+	left, right, ok := p.signedOperands()
+	if !ok {
+		return false
+	}
+	// Quotients round toward zero, and -128 / -1 overflows to -128
+	quotient := uint8(left / right)
+	p.storeResult(quotient)
+	p.setFlags(quotient, false, left == -128 && right == -1)
+	return true
+}
+
+func (p *Processor) executeSignedRemainder() bool {
+	left, right, ok := p.signedOperands()
+	if !ok {
+		return false
+	}
+	// The remainder has the sign of the left value
+	remainder := uint8(left % right)
+	p.storeResult(remainder)
+	p.setFlags(remainder, false, false)
+	return true
+}
+
+func (p *Processor) executeModulo() bool {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	if p.RegisterValue(registerRight) == 0x00 {
+		p.fault(ErrDivideByZero, "", nil)
+		return false
+	}
+	remainder := p.RegisterValue(registerLeft) % p.RegisterValue(registerRight)
+	p.storeResult(remainder)
+	p.setFlags(remainder, false, false)
 	return true
 }
 
@@ -244,16 +651,80 @@ func (p *Processor) executeJumpNotEqual() bool {
 	return true
 }
 
+// jumpIf fetches an address and sets IP to it if condition is true.
+func (p *Processor) jumpIf(condition bool) bool {
+	address := p.fetchAddressInstruction()
+	if condition {
+		p.instructionPointer = address
+	}
+	return true
+}
+
+// Signed comparisons use the negative and overflow flags
+
+func (p *Processor) executeJumpLessThan() bool {
+	return p.jumpIf(p.flagSet(FlagNegative) != p.flagSet(FlagOverflow))
+}
+
+func (p *Processor) executeJumpGreaterThan() bool {
+	return p.jumpIf(!p.flagSet(FlagZero) && p.flagSet(FlagNegative) == p.flagSet(FlagOverflow))
+}
+
+func (p *Processor) executeJumpLessEqual() bool {
+	return p.jumpIf(p.flagSet(FlagZero) || p.flagSet(FlagNegative) != p.flagSet(FlagOverflow))
+}
+
+func (p *Processor) executeJumpGreaterEqual() bool {
+	return p.jumpIf(p.flagSet(FlagNegative) == p.flagSet(FlagOverflow))
+}
+
+// Unsigned comparisons use the carry flag, which is set on borrow
+
+func (p *Processor) executeJumpLower() bool {
+	return p.jumpIf(p.flagSet(FlagCarry))
+}
+
+func (p *Processor) executeJumpHigher() bool {
+	return p.jumpIf(!p.flagSet(FlagCarry) && !p.flagSet(FlagZero))
+}
+
+func (p *Processor) executeJumpLowerSame() bool {
+	return p.jumpIf(p.flagSet(FlagCarry) || p.flagSet(FlagZero))
+}
+
+func (p *Processor) executeJumpHigherSame() bool {
+	return p.jumpIf(!p.flagSet(FlagCarry))
+}
+
+func (p *Processor) executeJumpCarrySet() bool {
+	return p.jumpIf(p.flagSet(FlagCarry))
+}
+
+func (p *Processor) executeJumpCarryClear() bool {
+	return p.jumpIf(!p.flagSet(FlagCarry))
+}
+
+func (p *Processor) executeJumpZeroSet() bool {
+	return p.jumpIf(p.flagSet(FlagZero))
+}
+
+func (p *Processor) executeJumpZeroClear() bool {
+	return p.jumpIf(!p.flagSet(FlagZero))
+}
+
 /*********
  * STACK *
  *********/
 
 func (p *Processor) stackPush(value uint8) {
 	if p.stackPointer == StackLimit {
-		p.errors = append(p.errors, errors.New("stack overflow"))
+		p.fault(ErrStackOverflow, "", nil)
 		return
 	}
-	p.memory.Write(p.stackPointer, value)
+	if !p.canAccess(p.stackPointer, true, true) {
+		return
+	}
+	p.storeMemory(p.stackPointer, value)
 	p.stackPointer++
 	p.stackSize++
 }
@@ -261,6 +732,9 @@ func (p *Processor) stackPush(value uint8) {
 func (p *Processor) stackPop() uint8 {
 	p.stackPointer--
 	p.stackSize--
+	if !p.canAccess(p.stackPointer, false, true) {
+		return 0x00
+	}
 	return p.memory.Read(p.stackPointer)
 }
 
@@ -280,11 +754,11 @@ func (p *Processor) executeStackPop() bool {
 	 * Because executeReturn pops from stackSize==0
 	 * when it fetches the IP and R values */
 	if p.stackSize == 0 || p.stackPointer == StackStart {
-		p.errors = append(p.errors, errors.New("stack underflow"))
+		p.fault(ErrStackUnderflow, "", nil)
 		return false
 	}
 	register := p.fetchInstruction()
-	p.setRegisterValue(register, p.stackPop())
+	p.SetRegisterValue(register, p.stackPop())
 	return true
 }
 
@@ -310,7 +784,7 @@ func (p *Processor) executeReturn() bool {
 	ip += uint16(p.stackPop()) << 8
 	p.instructionPointer = ip
 	for r := uint8(7); r > 1; r-- {
-		p.setRegisterValue(r, p.stackPop())
+		p.SetRegisterValue(r, p.stackPop())
 	}
 	p.stackSize = p.stackPop()
 	return true
@@ -324,7 +798,7 @@ func (p *Processor) executePrint() bool {
 	address := p.fetchAddressInstruction()
 	length := p.fetchInstruction()
 	for i := uint16(0); i < uint16(length); i++ {
-		fmt.Fprintf(p.writer, "%c", p.memory.Read(address+i))
+		fmt.Fprintf(p.writer, "%c", p.readMemory(address+i))
 	}
 	p.writer.Flush()
 	return true
@@ -334,9 +808,9 @@ func (p *Processor) executeReadInput() bool {
 	register := p.fetchInstruction()
 	c, err := p.reader.ReadByte()
 	if err != nil {
-		p.errors = append(p.errors, fmt.Errorf("error reading input: %s", err))
+		p.fault(ErrInput, "", err)
 	}
-	p.setRegisterValue(register, c)
+	p.SetRegisterValue(register, c)
 	return true
 }
 
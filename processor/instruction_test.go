@@ -104,6 +104,72 @@ func TestExecuteMoveMemReg(t *testing.T) {
 	}
 }
 
+func TestExecuteLoadStoreOffset(t *testing.T) {
+	tests := []struct {
+		base     uint16
+		offset   uint8
+		expected uint16
+	}{
+		{base: 0x1200, offset: 0x34, expected: 0x1234},
+		{base: 0x12F0, offset: 0x20, expected: 0x1310},
+		{base: 0xFFF0, offset: 0xFF, expected: 0x00EF}, // Wraps around
+	}
+
+	for _, test := range tests {
+		p, m := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitWord, highByte(test.base), lowByte(test.base), R2,
+			processor.MoveLitReg, 0x42, R1,
+			processor.StoreOffset, R1, R2, test.offset,
+			processor.LoadOffset, R2, test.offset, R5,
+		})
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if m.Read(test.expected) != 0x42 {
+			t.Errorf("got 0x%X at address 0x%04X for 0x%04X + 0x%X, want 0x42",
+				m.Read(test.expected), test.expected, test.base, test.offset)
+		}
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R5) != 0x42 {
+			t.Errorf("got 0x%X at R5 for 0x%04X + 0x%X, want 0x42", p.RegisterValue(R5), test.base, test.offset)
+		}
+	}
+}
+
+func TestExecuteLoadStoreIndexed(t *testing.T) {
+	tests := []struct {
+		base     uint16
+		index    uint8
+		expected uint16
+	}{
+		{base: 0x1200, index: 0x34, expected: 0x1234},
+		{base: 0x12F0, index: 0x20, expected: 0x1310},
+		{base: 0xFFFF, index: 0x01, expected: 0x0000}, // Wraps around
+	}
+
+	for _, test := range tests {
+		p, m := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitWord, highByte(test.base), lowByte(test.base), R2,
+			processor.MoveLitReg, 0x42, R1,
+			processor.MoveLitReg, test.index, R6,
+			processor.StoreIndexed, R1, R2, R6,
+			processor.LoadIndexed, R2, R6, R5,
+		})
+		p.Step()
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if m.Read(test.expected) != 0x42 {
+			t.Errorf("got 0x%X at address 0x%04X for 0x%04X + 0x%X, want 0x42",
+				m.Read(test.expected), test.expected, test.base, test.index)
+		}
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R5) != 0x42 {
+			t.Errorf("got 0x%X at R5 for 0x%04X + 0x%X, want 0x42", p.RegisterValue(R5), test.base, test.index)
+		}
+	}
+}
+
 func TestExecuteLogicalAnd(t *testing.T) {
 	tests := []struct {
 		inputLeft, inputRight, expected uint8
@@ -469,6 +535,72 @@ func TestExecuteJumpNotEqual(t *testing.T) {
 	}
 }
 
+func TestExecuteCompare(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{
+		processor.MoveLitReg, 0x42, R0,
+		processor.MoveLitReg, 0x43, R1,
+		processor.Compare, R0, R1,
+	})
+	p.Step()
+	p.Step()
+	stepAndCheckContinueValue(t, p, true)
+	if p.RegisterValue(R0) != 0x42 {
+		t.Errorf("got 0x%X at R0, want 0x42", p.RegisterValue(R0))
+	}
+	if p.Flags() != processor.FlagCarry|processor.FlagNegative {
+		t.Errorf("got %s, want -C-N", processor.FlagString(p.Flags()))
+	}
+}
+
+func TestConditionalJumps(t *testing.T) {
+	// Each comparison is CMP left, right followed by the jump
+	comparisons := []struct{ left, right uint8 }{
+		{left: 0x05, right: 0x05},
+		{left: 0x05, right: 0x06}, // 5 < 6
+		{left: 0x06, right: 0x05}, // 6 > 5
+		{left: 0xFF, right: 0x01}, // -1 < 1 signed, 255 > 1 unsigned
+		{left: 0x01, right: 0xFF}, // 1 > -1 signed, 1 < 255 unsigned
+		{left: 0x80, right: 0x7F}, // -128 < 127 signed, overflows
+	}
+	tests := []struct {
+		instruction uint8
+		expected    []bool // jump taken for each comparison
+	}{
+		{instruction: processor.JumpLessThan, expected: []bool{false, true, false, true, false, true}},
+		{instruction: processor.JumpGreaterThan, expected: []bool{false, false, true, false, true, false}},
+		{instruction: processor.JumpLessEqual, expected: []bool{true, true, false, true, false, true}},
+		{instruction: processor.JumpGreaterEqual, expected: []bool{true, false, true, false, true, false}},
+		{instruction: processor.JumpLower, expected: []bool{false, true, false, false, true, false}},
+		{instruction: processor.JumpHigher, expected: []bool{false, false, true, true, false, true}},
+		{instruction: processor.JumpLowerSame, expected: []bool{true, true, false, false, true, false}},
+		{instruction: processor.JumpHigherSame, expected: []bool{true, false, true, true, false, true}},
+		{instruction: processor.JumpCarrySet, expected: []bool{false, true, false, false, true, false}},
+		{instruction: processor.JumpCarryClear, expected: []bool{true, false, true, true, false, true}},
+		{instruction: processor.JumpZeroSet, expected: []bool{true, false, false, false, false, false}},
+		{instruction: processor.JumpZeroClear, expected: []bool{false, true, true, true, true, true}},
+	}
+
+	for _, test := range tests {
+		for i, comparison := range comparisons {
+			p, _ := newTestProcessorWithPogram([]uint8{
+				processor.MoveLitReg, comparison.left, R1,
+				processor.MoveLitReg, comparison.right, R2,
+				processor.Compare, R1, R2,
+				test.instruction, 0xAB, 0xCD,
+			})
+			p.Step()
+			p.Step()
+			p.Step()
+			stepAndCheckContinueValue(t, p, true)
+			taken := p.InstructionPointer() == 0xABCD
+			if taken != test.expected[i] {
+				t.Errorf("got jump taken %t for 0x%X after CMP 0x%X, 0x%X, want %t",
+					taken, test.instruction, comparison.left, comparison.right, test.expected[i])
+			}
+		}
+	}
+}
+
 func TestExecuteStackPushLit(t *testing.T) {
 	p, m := newTestProcessorWithPogram([]uint8{
 		processor.StackPushLit, 0x13,
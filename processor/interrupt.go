@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+const (
+	InterruptLineCount   uint8  = 8
+	InterruptVectorTable uint16 = 0xFEF0 // Handler address (High Byte, Low Byte) for each line
+)
+
+func (p *Processor) InterruptsEnabled() bool {
+	return p.interruptsEnabled
+}
+
+// RaiseInterrupt marks an interrupt line as pending.  It is safe to call
+// from other goroutines, such as those running devices.  Pending
+// interrupts are serviced before the next instruction while interrupts
+// are enabled.
+func (p *Processor) RaiseInterrupt(line uint8) error {
+	if line >= InterruptLineCount {
+		return fmt.Errorf("invalid interrupt line: %d", line)
+	}
+	for {
+		pending := atomic.LoadUint32(&p.pendingInterrupts)
+		if atomic.CompareAndSwapUint32(&p.pendingInterrupts, pending, pending|1<<line) {
+			return nil
+		}
+	}
+}
+
+// takeInterrupt clears and returns the lowest pending interrupt line.
+func (p *Processor) takeInterrupt() uint8 {
+	for {
+		pending := atomic.LoadUint32(&p.pendingInterrupts)
+		line := uint8(0)
+		for pending&(1<<line) == 0 {
+			line++
+		}
+		if atomic.CompareAndSwapUint32(&p.pendingInterrupts, pending, pending&^(1<<line)) {
+			return line
+		}
+	}
+}
+
+// serviceInterrupt enters the handler for the lowest pending line.  The
+// frame is saved like executeCall, but all registers and the flags are
+// pushed because the interrupted code does not expect them to change.
+// Lines with a handler address of 0x0000 are ignored.
+func (p *Processor) serviceInterrupt() {
+	line := p.takeInterrupt()
+	vector := InterruptVectorTable + 2*uint16(line)
+	handler := (uint16(p.memory.Read(vector)) << 8) + uint16(p.memory.Read(vector+1))
+	if handler == 0x0000 {
+		return
+	}
+
+	p.stackPush(p.stackSize)
+	for r := uint8(0); r < RegisterCount; r++ {
+		p.stackPush(p.RegisterValue(r))
+	}
+	p.stackPush(p.flags)
+	p.stackPush(uint8(p.instructionPointer >> 8))
+	p.stackPush(uint8(p.instructionPointer))
+	p.stackSize = 0
+	p.interruptsEnabled = false
+	p.instructionPointer = handler
+}
+
+func (p *Processor) executeReturnInterrupt() bool {
+	for i := uint8(0); i < p.stackSize; i++ {
+		p.stackPop() // Current stack falls out of scope
+	}
+	ip := uint16(p.stackPop())
+	ip += uint16(p.stackPop()) << 8
+	p.instructionPointer = ip
+	p.flags = p.stackPop()
+	for r := int(RegisterCount) - 1; r >= 0; r-- {
+		p.SetRegisterValue(uint8(r), p.stackPop())
+	}
+	p.stackSize = p.stackPop()
+	p.interruptsEnabled = true
+	return true
+}
+
+func (p *Processor) executeEnableInterrupts() bool {
+	p.interruptsEnabled = true
+	return true
+}
+
+func (p *Processor) executeDisableInterrupts() bool {
+	p.interruptsEnabled = false
+	return true
+}
@@ -0,0 +1,130 @@
+package processor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+// setVector sets the handler address for an interrupt line.
+func setVector(m interface{ Write(uint16, uint8) }, line uint8, handler uint16) {
+	vector := processor.InterruptVectorTable + 2*uint16(line)
+	m.Write(vector, highByte(handler))
+	m.Write(vector+1, lowByte(handler))
+}
+
+func TestInterruptEntryAndReturn(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{
+		processor.EnableInterrupts,     // 0x0000
+		processor.MoveLitReg, 0x11, R0, // 0x0001
+		processor.Add, R0, R0, // 0x0004 (Sets R0 and flags before the interrupt)
+		processor.Noop,                 // 0x0007
+		processor.Halt,                 // 0x0008
+		processor.MoveLitReg, 0x99, R0, // 0x0009 Handler
+		processor.MoveLitReg, 0x99, R7,
+		processor.Subtract, R0, R7, // Changes flags
+		processor.ReturnInterrupt,
+	})
+	setVector(m, 3, 0x0009)
+	p.Step()
+	if !p.InterruptsEnabled() {
+		t.Fatal("interrupts not enabled after EI")
+	}
+	p.Step()
+	p.Step()
+	if err := p.RaiseInterrupt(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entry happens before the instruction at the handler
+	stepAndCheckContinueValue(t, p, true)
+	if p.InstructionPointer() != 0x000C || p.RegisterValue(R0) != 0x99 {
+		t.Errorf("got IP 0x%X and R0 0x%X, want 0x000C and 0x99", p.InstructionPointer(), p.RegisterValue(R0))
+	}
+	if p.InterruptsEnabled() {
+		t.Error("interrupts enabled in handler")
+	}
+	if p.StackPointer() != 0xFF0C || p.StackSize() != 0 {
+		t.Errorf("got SP 0x%X and stack size %d, want 0xFF0C and 0", p.StackPointer(), p.StackSize())
+	}
+
+	p.Step()
+	p.Step()
+	stepAndCheckContinueValue(t, p, true) // RTI
+	if p.InstructionPointer() != 0x0007 {
+		t.Errorf("got 0x%X at IP, want 0x0007", p.InstructionPointer())
+	}
+	if p.RegisterValue(R0) != 0x22 || p.RegisterValue(R7) != 0x00 {
+		t.Errorf("got 0x%X at R0 and 0x%X at R7, want 0x22 and 0x00", p.RegisterValue(R0), p.RegisterValue(R7))
+	}
+	if p.Flags() != 0 {
+		t.Errorf("got %s, want ----", processor.FlagString(p.Flags()))
+	}
+	if !p.InterruptsEnabled() || p.StackPointer() != 0xFF00 {
+		t.Errorf("got interrupts %t and SP 0x%X, want true and 0xFF00", p.InterruptsEnabled(), p.StackPointer())
+	}
+}
+
+func TestInterruptsDisabled(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{
+		processor.EnableInterrupts,
+		processor.DisableInterrupts,
+		processor.Noop,
+		processor.EnableInterrupts,
+		processor.Noop,
+	})
+	setVector(m, 0, 0x1234)
+	p.Step()
+	p.Step()
+	p.RaiseInterrupt(0)
+	p.Step()
+	if p.InstructionPointer() != 0x0003 {
+		t.Errorf("got 0x%X at IP, want 0x0003 while interrupts disabled", p.InstructionPointer())
+	}
+	p.Step() // EI
+	p.Step() // Pending interrupt is serviced
+	if p.InstructionPointer() != 0x1235 {
+		t.Errorf("got 0x%X at IP, want 0x1235 after interrupts enabled", p.InstructionPointer())
+	}
+}
+
+func TestInterruptPriorityAndUnhandledLines(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{processor.EnableInterrupts, processor.Noop})
+	setVector(m, 5, 0x2000)
+	m.Write(0x2000, processor.EnableInterrupts)
+	p.Step()
+	p.RaiseInterrupt(5)
+	p.RaiseInterrupt(2) // No handler, ignored
+	p.Step()
+	if p.InstructionPointer() != 0x0002 {
+		t.Errorf("got 0x%X at IP, want 0x0002 after unhandled line 2", p.InstructionPointer())
+	}
+	p.Step()
+	if p.InstructionPointer() != 0x2001 {
+		t.Errorf("got 0x%X at IP, want 0x2001 for line 5", p.InstructionPointer())
+	}
+}
+
+func TestRaiseInterruptInvalidLine(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{})
+	if err := p.RaiseInterrupt(processor.InterruptLineCount); err == nil {
+		t.Error("got nil, want error for invalid interrupt line")
+	}
+}
+
+func TestRaiseInterruptFromGoroutine(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{
+		processor.EnableInterrupts,
+		processor.Jump, 0x00, 0x01, // Wait for the interrupt
+	})
+	setVector(m, 1, 0x3000)
+	m.Write(0x3000, processor.Halt)
+	go func() {
+		time.Sleep(time.Millisecond)
+		p.RaiseInterrupt(1)
+	}()
+	if status := p.Run(); status != 0 {
+		t.Errorf("got status %d, want 0", status)
+	}
+}
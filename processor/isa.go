@@ -0,0 +1,245 @@
+package processor
+
+import "strings"
+
+// ISAVersion is the version of the instruction set.  It is increased
+// when instructions are added or changed.
+const ISAVersion uint8 = 7
+
+// OperandKind describes how an instruction operand is encoded in bytecode.
+type OperandKind uint8
+
+const (
+	OperandRegister OperandKind = iota // One byte register number
+	OperandLiteral                     // One byte literal value
+	OperandAddress                     // Two byte address (High Byte, Low Byte)
+	OperandWord                        // Two byte literal value (High Byte, Low Byte)
+	OperandOffset                      // One byte signed offset
+)
+
+// Size returns the number of bytecode bytes used by the operand.
+func (k OperandKind) Size() uint16 {
+	if k == OperandAddress || k == OperandWord {
+		return 2
+	}
+	return 1
+}
+
+// FlowKind describes where execution continues after an instruction.
+type FlowKind uint8
+
+const (
+	FlowNext   FlowKind = iota // Continue at the next instruction
+	FlowJump                   // Continue at the address operand
+	FlowBranch                 // Continue at the address operand or the next instruction
+	FlowCall                   // Continue at the address operand, then return to the next instruction
+	FlowReturn                 // Continue at an address taken from the stack
+	FlowHalt                   // Stop execution
+)
+
+// InstructionInfo describes the encoding of a single instruction.
+type InstructionInfo struct {
+	Opcode   uint8
+	Mnemonic string
+	Operands []OperandKind
+	Flow     FlowKind
+}
+
+// Size returns the number of bytecode bytes used by the instruction,
+// including the opcode.
+func (i InstructionInfo) Size() uint16 {
+	size := uint16(1)
+	for _, operand := range i.Operands {
+		size += operand.Size()
+	}
+	return size
+}
+
+// Short names for the instruction set table
+const (
+	opReg  = OperandRegister
+	opLit  = OperandLiteral
+	opAddr = OperandAddress
+	opWord = OperandWord
+	opOff  = OperandOffset
+)
+
+var instructionSet = []InstructionInfo{
+	{Opcode: Noop, Mnemonic: "NOP"},
+	{Opcode: MoveLitReg, Mnemonic: "MLR", Operands: []OperandKind{opLit, opReg}},
+	{Opcode: MoveRegReg, Mnemonic: "MRR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: MoveLitMem, Mnemonic: "MLM", Operands: []OperandKind{opLit, opReg}},
+	{Opcode: MoveRegMem, Mnemonic: "MRM", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: MoveMemReg, Mnemonic: "MMR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: MoveLitWord, Mnemonic: "MLW", Operands: []OperandKind{opWord, opReg}},
+	{Opcode: LoadOffset, Mnemonic: "LDO", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: LoadIndexed, Mnemonic: "LDX", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: StoreOffset, Mnemonic: "STO", Operands: []OperandKind{opReg, opReg, opLit}},
+	{Opcode: StoreIndexed, Mnemonic: "STX", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: LogicalAnd, Mnemonic: "LND", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: LogicalOr, Mnemonic: "LOR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: LogicalXor, Mnemonic: "LXR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: LogicalBitClear, Mnemonic: "LBC", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: LogicalShiftLeft, Mnemonic: "LSL", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: LogicalShiftRight, Mnemonic: "LSR", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: ArithShiftRight, Mnemonic: "ASR", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: LogicalAndLit, Mnemonic: "ANI", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: LogicalOrLit, Mnemonic: "ORI", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: LogicalXorLit, Mnemonic: "XRI", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: ShiftLeftReg, Mnemonic: "LLR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: ShiftRightReg, Mnemonic: "LRR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: ArithShiftReg, Mnemonic: "ARR", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Inc, Mnemonic: "INC", Operands: []OperandKind{opReg}},
+	{Opcode: Dec, Mnemonic: "DEC", Operands: []OperandKind{opReg}},
+	{Opcode: Add, Mnemonic: "ADD", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Subtract, Mnemonic: "SUB", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Multiply, Mnemonic: "MUL", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Divide, Mnemonic: "DIV", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Compare, Mnemonic: "CMP", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Negate, Mnemonic: "NEG", Operands: []OperandKind{opReg}},
+	{Opcode: SignedMultiply, Mnemonic: "SML", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: SignedDivide, Mnemonic: "SDV", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: SignedRemainder, Mnemonic: "SRM", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Modulo, Mnemonic: "MOD", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: AddLit, Mnemonic: "ADI", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: SubtractLit, Mnemonic: "SBI", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: CompareLit, Mnemonic: "CPI", Operands: []OperandKind{opReg, opLit}},
+	{Opcode: IncWord, Mnemonic: "INW", Operands: []OperandKind{opReg}},
+	{Opcode: DecWord, Mnemonic: "DCW", Operands: []OperandKind{opReg}},
+	{Opcode: AddWord, Mnemonic: "ADW", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: SubtractWord, Mnemonic: "SBW", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: CompareWord, Mnemonic: "CPW", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: SignExtend, Mnemonic: "SXT", Operands: []OperandKind{opReg, opReg}},
+	{Opcode: Jump, Mnemonic: "JMP", Operands: []OperandKind{opAddr}, Flow: FlowJump},
+	{Opcode: JumpEqual, Mnemonic: "JEQ", Operands: []OperandKind{opReg, opAddr}, Flow: FlowBranch},
+	{Opcode: JumpNotEqual, Mnemonic: "JNE", Operands: []OperandKind{opReg, opAddr}, Flow: FlowBranch},
+	{Opcode: JumpLessThan, Mnemonic: "JLT", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpGreaterThan, Mnemonic: "JGT", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpLessEqual, Mnemonic: "JLE", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpGreaterEqual, Mnemonic: "JGE", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpLower, Mnemonic: "JLO", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpHigher, Mnemonic: "JHI", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpLowerSame, Mnemonic: "JLS", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpHigherSame, Mnemonic: "JHS", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpCarrySet, Mnemonic: "JCS", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpCarryClear, Mnemonic: "JCC", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpZeroSet, Mnemonic: "JZS", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: JumpZeroClear, Mnemonic: "JZC", Operands: []OperandKind{opAddr}, Flow: FlowBranch},
+	{Opcode: StackPushLit, Mnemonic: "SPL", Operands: []OperandKind{opLit}},
+	{Opcode: StackPushReg, Mnemonic: "SPR", Operands: []OperandKind{opReg}},
+	{Opcode: StackPop, Mnemonic: "STP", Operands: []OperandKind{opReg}},
+	{Opcode: Call, Mnemonic: "CLL", Operands: []OperandKind{opAddr}, Flow: FlowCall},
+	{Opcode: Return, Mnemonic: "RET", Flow: FlowReturn},
+	{Opcode: ReturnInterrupt, Mnemonic: "RTI", Flow: FlowReturn},
+	{Opcode: LoadFrame, Mnemonic: "LDF", Operands: []OperandKind{opOff, opReg}},
+	{Opcode: StoreFrame, Mnemonic: "STF", Operands: []OperandKind{opReg, opOff}},
+	{Opcode: Drop, Mnemonic: "DRP", Operands: []OperandKind{opLit}},
+	{Opcode: LogicalAndDst, Mnemonic: "LND", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: LogicalOrDst, Mnemonic: "LOR", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: LogicalXorDst, Mnemonic: "LXR", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: LogicalBitClearDst, Mnemonic: "LBC", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: LogicalShiftLeftDst, Mnemonic: "LSL", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: LogicalShiftRightDst, Mnemonic: "LSR", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: ArithShiftRightDst, Mnemonic: "ASR", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: LogicalAndLitDst, Mnemonic: "ANI", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: LogicalOrLitDst, Mnemonic: "ORI", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: LogicalXorLitDst, Mnemonic: "XRI", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: ShiftLeftRegDst, Mnemonic: "LLR", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: ShiftRightRegDst, Mnemonic: "LRR", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: ArithShiftRegDst, Mnemonic: "ARR", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: AddDst, Mnemonic: "ADD", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: SubtractDst, Mnemonic: "SUB", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: MultiplyDst, Mnemonic: "MUL", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: DivideDst, Mnemonic: "DIV", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: SignedMultiplyDst, Mnemonic: "SML", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: SignedDivideDst, Mnemonic: "SDV", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: SignedRemainderDst, Mnemonic: "SRM", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: ModuloDst, Mnemonic: "MOD", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: AddLitDst, Mnemonic: "ADI", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: SubtractLitDst, Mnemonic: "SBI", Operands: []OperandKind{opReg, opLit, opReg}},
+	{Opcode: AddWordDst, Mnemonic: "ADW", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: SubtractWordDst, Mnemonic: "SBW", Operands: []OperandKind{opReg, opReg, opReg}},
+	{Opcode: Print, Mnemonic: "PNT", Operands: []OperandKind{opAddr, opLit}},
+	{Opcode: ReadInput, Mnemonic: "RIN", Operands: []OperandKind{opReg}},
+	{Opcode: EnableInterrupts, Mnemonic: "EI"},
+	{Opcode: DisableInterrupts, Mnemonic: "DI"},
+	{Opcode: Halt, Mnemonic: "HLT", Flow: FlowHalt},
+}
+
+var (
+	instructionsByOpcode   = map[uint8]InstructionInfo{}
+	instructionsByMnemonic = map[string]InstructionInfo{}   // The first form of each mnemonic
+	instructionForms       = map[string][]InstructionInfo{} // Every form of each mnemonic
+	instructionSizes       [0xFF + 1]uint8                  // Size of each instruction, or 0 if unknown
+)
+
+func init() {
+	for _, info := range instructionSet {
+		if info.Size() > maxOperandBytes+1 {
+			panic("instruction " + info.Mnemonic + " has too many operand bytes")
+		}
+		instructionsByOpcode[info.Opcode] = info
+		if _, found := instructionsByMnemonic[info.Mnemonic]; !found {
+			instructionsByMnemonic[info.Mnemonic] = info
+		}
+		instructionForms[info.Mnemonic] = append(instructionForms[info.Mnemonic], info)
+		instructionSizes[info.Opcode] = uint8(info.Size())
+	}
+}
+
+// Target returns the index of the operand holding the address used by
+// jumps, branches and calls, or -1 if the instruction has no target.
+func (i InstructionInfo) Target() int {
+	if i.Flow != FlowJump && i.Flow != FlowBranch && i.Flow != FlowCall {
+		return -1
+	}
+	for index, operand := range i.Operands {
+		if operand == OperandAddress {
+			return index
+		}
+	}
+	return -1
+}
+
+// InstructionSet returns the encoding of every instruction, ordered by opcode.
+func InstructionSet() []InstructionInfo {
+	out := make([]InstructionInfo, len(instructionSet))
+	copy(out, instructionSet)
+	return out
+}
+
+// LookupOpcode returns the encoding of the instruction with the given opcode.
+func LookupOpcode(opcode uint8) (InstructionInfo, bool) {
+	info, found := instructionsByOpcode[opcode]
+	return info, found
+}
+
+// LookupMnemonic returns the encoding of the instruction with the given
+// mnemonic.  The lookup is not case sensitive.  For mnemonics with more
+// than one form, it returns the form with the lowest opcode.
+func LookupMnemonic(mnemonic string) (InstructionInfo, bool) {
+	info, found := instructionsByMnemonic[strings.ToUpper(mnemonic)]
+	return info, found
+}
+
+// LookupForm returns the encoding of the instruction with the given
+// mnemonic and number of operands, such as the form of an ALU
+// instruction that names a destination register.  The lookup is not case
+// sensitive.
+func LookupForm(mnemonic string, operands int) (InstructionInfo, bool) {
+	for _, info := range instructionForms[strings.ToUpper(mnemonic)] {
+		if len(info.Operands) == operands {
+			return info, true
+		}
+	}
+	return InstructionInfo{}, false
+}
+
+// Forms returns every encoding of the instruction with the given
+// mnemonic, ordered by opcode.  The lookup is not case sensitive.
+func Forms(mnemonic string) []InstructionInfo {
+	forms := instructionForms[strings.ToUpper(mnemonic)]
+	out := make([]InstructionInfo, len(forms))
+	copy(out, forms)
+	return out
+}
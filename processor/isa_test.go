@@ -0,0 +1,97 @@
+package processor_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestInstructionSetLookups(t *testing.T) {
+	for _, info := range processor.InstructionSet() {
+		byOpcode, found := processor.LookupOpcode(info.Opcode)
+		if !found || byOpcode.Mnemonic != info.Mnemonic {
+			t.Errorf("LookupOpcode(0x%X) did not return %s", info.Opcode, info.Mnemonic)
+		}
+		byForm, found := processor.LookupForm(info.Mnemonic, len(info.Operands))
+		if !found || byForm.Opcode != info.Opcode {
+			t.Errorf("LookupForm(%s, %d) did not return 0x%X", info.Mnemonic, len(info.Operands), info.Opcode)
+		}
+	}
+	if _, found := processor.LookupMnemonic("hlt"); !found {
+		t.Error("LookupMnemonic is case sensitive")
+	}
+	if info, _ := processor.LookupMnemonic("ADD"); info.Opcode != processor.Add {
+		t.Errorf("LookupMnemonic(ADD) returned 0x%X, want the R0 form 0x%X", info.Opcode, processor.Add)
+	}
+	if forms := processor.Forms("add"); len(forms) != 2 || forms[1].Opcode != processor.AddDst {
+		t.Errorf("got forms %v for ADD, want ADD and its destination form", forms)
+	}
+	if _, found := processor.LookupForm("ADD", 1); found {
+		t.Error("LookupForm found ADD with one operand")
+	}
+	if _, found := processor.LookupOpcode(0x0F); found {
+		t.Error("LookupOpcode found unknown instruction 0x0F")
+	}
+}
+
+func TestInstructionSetMatchesExecution(t *testing.T) {
+	// Every instruction in the set must execute, and every
+	// other opcode must be reported as an unknown instruction.
+	for opcode := 0; opcode <= 0xFF; opcode++ {
+		if uint8(opcode) == processor.ReadInput {
+			continue // Would block reading from stdin
+		}
+		_, documented := processor.LookupOpcode(uint8(opcode))
+		p, _ := newTestProcessorWithPogram([]uint8{uint8(opcode), 0x00, 0x00, 0x00})
+		p.Step()
+		unknown := false
+		for _, err := range p.Errors() {
+			if err.Error() == fmt.Sprintf("unknown instruction 0x%X at position 0x0", opcode) {
+				unknown = true
+			}
+		}
+		if documented == unknown {
+			t.Errorf("opcode 0x%X documented: %t, executed: %t", opcode, documented, !unknown)
+		}
+	}
+}
+
+func TestInstructionSize(t *testing.T) {
+	tests := []struct {
+		opcode   uint8
+		expected uint16
+	}{
+		{opcode: processor.Noop, expected: 1},
+		{opcode: processor.Inc, expected: 2},
+		{opcode: processor.MoveLitReg, expected: 3},
+		{opcode: processor.JumpEqual, expected: 4},
+		{opcode: processor.Print, expected: 4},
+		{opcode: processor.AddDst, expected: 4},
+	}
+	for _, test := range tests {
+		info, _ := processor.LookupOpcode(test.opcode)
+		if info.Size() != test.expected {
+			t.Errorf("got size %d for %s, want %d", info.Size(), info.Mnemonic, test.expected)
+		}
+	}
+}
+
+func TestInstructionTarget(t *testing.T) {
+	tests := []struct {
+		opcode   uint8
+		expected int
+	}{
+		{opcode: processor.Jump, expected: 0},
+		{opcode: processor.JumpEqual, expected: 1},
+		{opcode: processor.Call, expected: 0},
+		{opcode: processor.Print, expected: -1}, // Address operand is data
+		{opcode: processor.Return, expected: -1},
+	}
+	for _, test := range tests {
+		info, _ := processor.LookupOpcode(test.opcode)
+		if info.Target() != test.expected {
+			t.Errorf("got target %d for %s, want %d", info.Target(), info.Mnemonic, test.expected)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrStepLimit = errors.New("step limit reached")
+	ErrOutOfFuel = errors.New("out of fuel")
+)
+
+// LimitError reports that execution stopped at a step or fuel limit, or
+// because the context passed to RunContext was done.
+type LimitError struct {
+	Err                error  // ErrStepLimit, ErrOutOfFuel or the context error
+	InstructionPointer uint16 // Address of the next instruction
+	Steps              uint64 // Instructions executed before stopping
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s at position 0x%X after %d steps", e.Err, e.InstructionPointer, e.Steps)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// WithStepLimit stops execution with ErrStepLimit before the instruction
+// after limit instructions.  A limit of 0 means no limit.
+func WithStepLimit(limit uint64) Option {
+	return func(p *Processor) {
+		p.stepLimit = limit
+	}
+}
+
+// WithFuel stops execution with ErrOutOfFuel before an instruction that
+// costs more than the remaining fuel.  Each instruction costs the value
+// for its opcode in costs, or 1 if the opcode is not in costs.  A limit
+// of 0 means no limit.
+func WithFuel(limit uint64, costs map[uint8]uint64) Option {
+	return func(p *Processor) {
+		p.fuelLimit = limit
+		p.fuelCosts = costs
+	}
+}
+
+// Steps returns the number of instructions executed.
+func (p *Processor) Steps() uint64 {
+	return p.steps
+}
+
+// FuelUsed returns the fuel used by executed instructions.
+func (p *Processor) FuelUsed() uint64 {
+	return p.fuelUsed
+}
+
+// chargeLimits counts the instruction at the IP against the step and fuel
+// limits.  It returns false if a limit has been reached.
+func (p *Processor) chargeLimits() bool {
+	if p.stepLimit > 0 && p.steps >= p.stepLimit {
+		p.stopAtLimit(ErrStepLimit)
+		return false
+	}
+	if p.fuelLimit > 0 {
+		cost, found := p.fuelCosts[p.memory.Read(p.instructionPointer)]
+		if !found {
+			cost = 1
+		}
+		if cost > p.fuelLimit-p.fuelUsed {
+			p.stopAtLimit(ErrOutOfFuel)
+			return false
+		}
+		p.fuelUsed += cost
+	}
+	p.steps++
+	return true
+}
+
+func (p *Processor) stopAtLimit(err error) {
+	p.instructionStart = p.instructionPointer
+	p.errors = append(p.errors, &LimitError{
+		Err:                err,
+		InstructionPointer: p.instructionPointer,
+		Steps:              p.steps,
+	})
+	p.faulted = true
+}
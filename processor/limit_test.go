@@ -0,0 +1,86 @@
+package processor_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func newTestProcessorWithOptions(program []uint8, options ...processor.Option) *processor.Processor {
+	m := memory.New()
+	m.LoadProgram(program)
+	return processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+		options...,
+	)
+}
+
+var infiniteLoop = []uint8{
+	processor.Noop,             // 0x0000
+	processor.Jump, 0x00, 0x00, // 0x0001
+}
+
+func checkLimitError(t *testing.T, p *processor.Processor, target error, ip uint16, steps uint64) {
+	t.Helper()
+	errs := p.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want one LimitError", errs)
+	}
+	var limitErr *processor.LimitError
+	if !errors.As(errs[0], &limitErr) || !errors.Is(errs[0], target) {
+		t.Fatalf("got %v, want LimitError for %v", errs[0], target)
+	}
+	if limitErr.InstructionPointer != ip || limitErr.Steps != steps {
+		t.Errorf("got IP 0x%04X after %d steps, want 0x%04X after %d", limitErr.InstructionPointer, limitErr.Steps, ip, steps)
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	p := newTestProcessorWithOptions(infiniteLoop, processor.WithStepLimit(5))
+	if status := p.Run(); status != 1 {
+		t.Errorf("got status %d, want 1", status)
+	}
+	checkLimitError(t, p, processor.ErrStepLimit, 0x0001, 5)
+
+	p = newTestProcessorWithOptions([]uint8{processor.Noop, processor.Halt}, processor.WithStepLimit(2))
+	if status := p.Run(); status != 0 {
+		t.Errorf("got status %d, want 0 for a program within the limit", status)
+	}
+}
+
+func TestFuel(t *testing.T) {
+	costs := map[uint8]uint64{processor.Jump: 3}
+	p := newTestProcessorWithOptions(infiniteLoop, processor.WithFuel(10, costs))
+	p.Run()
+	// NOP (1) + JMP (3) + NOP (1) + JMP (3) + NOP (1) leaves 1, too little for JMP
+	checkLimitError(t, p, processor.ErrOutOfFuel, 0x0001, 5)
+	if p.FuelUsed() != 9 {
+		t.Errorf("got fuel used %d, want 9", p.FuelUsed())
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	p := newTestProcessorWithOptions(infiniteLoop)
+	if status := p.RunContext(ctx); status != 1 {
+		t.Errorf("got status %d, want 1", status)
+	}
+	errs := p.Errors()
+	var limitErr *processor.LimitError
+	if len(errs) != 1 || !errors.As(errs[0], &limitErr) || !errors.Is(errs[0], context.DeadlineExceeded) {
+		t.Fatalf("got errors %v, want LimitError for context.DeadlineExceeded", errs)
+	}
+	if limitErr.Steps != p.Steps() || limitErr.Steps == 0 {
+		t.Errorf("got %d steps in error, want %d", limitErr.Steps, p.Steps())
+	}
+}
@@ -2,8 +2,10 @@ package processor
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 )
 
 const (
@@ -12,6 +14,10 @@ const (
 	StackLimit    uint16 = 0xFFFF
 )
 
+// MemoryDevice is the memory a Processor executes from.  Instructions are
+// decoded ahead of execution, so the contents must only change through
+// the Processor, or the device must be a VolatileMemoryDevice, or
+// InvalidateDecodeCache must be called after other writes.
 type MemoryDevice interface {
 	Write(address uint16, value uint8)
 	Read(address uint16) uint8
@@ -22,28 +28,80 @@ type Processor struct {
 	memory             MemoryDevice
 	registers          [RegisterCount]uint8
 	instructionPointer uint16
+	instructionStart   uint16        // address of the executing instruction
+	flags              uint8         // status flags set by arithmetic and logic
 	errors             []error       // errors encountered during execution
+	faulted            bool          // set when an error is recorded
 	stackPointer       uint16        // absolution position of top of stack in memory
 	stackSize          uint8         // size of current stack call frame
+	interruptsEnabled  bool          // set by EI and cleared by DI
+	pendingInterrupts  uint32        // bit per raised line, accessed atomically
+	stopRequested      int32         // set by Stop, accessed atomically
 	reader             *bufio.Reader // reader for input to RIN
 	writer             *bufio.Writer // writer for output from PNT
 	errorWriter        *bufio.Writer // writer for execution errors
+	tracers            []Tracer      // receive a record of each executed instruction
+	trace              *TraceRecord  // record for the executing instruction while tracing
+	symbols            Symbolizer    // describes error locations
+	steps              uint64        // instructions executed
+	stepLimit          uint64        // maximum steps, or 0 for no limit
+	fuelUsed           uint64        // fuel used by executed instructions
+	fuelLimit          uint64        // maximum fuel, or 0 for no limit
+	fuelCosts          map[uint8]uint64
+	protection         *[0xFFFF + 1]Protection // protection of each address, or nil
+	stackGuarded       bool                    // stack operations must use StackOnly regions
+	decodeCache        [0xFF + 1]*decodePage   // decoded instructions, by page of start address
+	operands           []uint8                 // remaining operands of a decoded instruction
+	volatile           VolatileMemoryDevice    // memory, if its contents can change by itself
+	generation         uint64                  // generation of volatile when the cache was filled
+	namedDestination   bool                    // the executing ALU instruction names its destination
 }
 
-func New(m MemoryDevice, r *bufio.Reader, w, ew *bufio.Writer) *Processor {
-	return &Processor{
+// Option configures optional Processor behaviour.
+type Option func(*Processor)
+
+// Symbolizer describes an address using debug symbols, such as its
+// source line and nearest label.
+type Symbolizer interface {
+	Describe(address uint16) string
+}
+
+// WithSymbols adds the source location of the failing instruction to
+// the errors written by Run.
+func WithSymbols(s Symbolizer) Option {
+	return func(p *Processor) {
+		p.symbols = s
+	}
+}
+
+func New(m MemoryDevice, r *bufio.Reader, w, ew *bufio.Writer, options ...Option) *Processor {
+	p := &Processor{
 		memory:       m,
 		stackPointer: StackStart,
 		reader:       r,
 		writer:       w,
 		errorWriter:  ew,
 	}
+	p.volatile, _ = m.(VolatileMemoryDevice)
+	if p.volatile != nil {
+		p.generation = p.volatile.Generation()
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
 }
 
 func (p *Processor) InstructionPointer() uint16 {
 	return p.instructionPointer
 }
 
+// SetInstructionPointer sets the address of the next instruction, such
+// as the entry point of an executable.
+func (p *Processor) SetInstructionPointer(address uint16) {
+	p.instructionPointer = address
+}
+
 func (p *Processor) StackPointer() uint16 {
 	return p.stackPointer
 }
@@ -60,15 +118,15 @@ func (p *Processor) Errors() []error {
 
 func (p *Processor) RegisterValue(register uint8) uint8 {
 	if register >= RegisterCount {
-		p.errors = append(p.errors, fmt.Errorf("invalid register access: %d", register))
+		p.fault(ErrInvalidRegister, fmt.Sprintf("R%d", register), nil)
 		return 0x00
 	}
 	return p.registers[register]
 }
 
-func (p *Processor) setRegisterValue(register uint8, value uint8) {
+func (p *Processor) SetRegisterValue(register uint8, value uint8) {
 	if register >= RegisterCount {
-		p.errors = append(p.errors, fmt.Errorf("invalid register access: %d", register))
+		p.fault(ErrInvalidRegister, fmt.Sprintf("R%d", register), nil)
 		return
 	}
 	p.registers[register] = value
@@ -81,11 +139,20 @@ func (p *Processor) registerPointerValue(register uint8) uint16 {
 }
 
 func (p *Processor) fetchInstruction() uint8 {
+	if len(p.operands) > 0 {
+		value := p.operands[0]
+		p.operands = p.operands[1:]
+		p.instructionPointer++
+		return value
+	}
+	if !p.canExecute(p.instructionPointer) {
+		return 0x00
+	}
 	instruction := p.memory.Read(p.instructionPointer)
 	p.instructionPointer++
 	// Detect instructionPointer overflow
 	if p.instructionPointer == 0x0000 {
-		p.errors = append(p.errors, errors.New("instruction pointer out of memory bounds"))
+		p.fault(ErrIPOutOfBounds, "", nil)
 		return 0x00
 	}
 	return instruction
@@ -97,30 +164,127 @@ func (p *Processor) fetchAddressInstruction() uint16 {
 	return (uint16(highByte) << 8) + uint16(lowByte)
 }
 
+// writeMemory writes address for an instruction that is not a stack
+// operation.
+func (p *Processor) writeMemory(address uint16, value uint8) {
+	if p.canAccess(address, true, false) {
+		p.storeMemory(address, value)
+	}
+}
+
+func (p *Processor) storeMemory(address uint16, value uint8) {
+	if p.trace != nil {
+		p.trace.MemoryWrites = append(p.trace.MemoryWrites, MemoryWrite{Address: address, Value: value})
+	}
+	p.memory.Write(address, value)
+	p.invalidateDecoded(address)
+}
+
 func (p *Processor) Step() bool {
-	instruction := p.fetchInstruction()
+	if p.interruptsEnabled && atomic.LoadUint32(&p.pendingInterrupts) != 0 {
+		p.instructionStart = p.instructionPointer
+		p.serviceInterrupt()
+		if p.faulted {
+			return false
+		}
+	}
+	if !p.chargeLimits() {
+		return false
+	}
+	if len(p.tracers) > 0 {
+		return p.tracedStep()
+	}
+	return p.step()
+}
+
+func (p *Processor) step() bool {
+	p.instructionStart = p.instructionPointer
+	if d := p.decoded(p.instructionPointer); d != nil {
+		p.operands = d.operands[:d.size-1]
+		p.instructionPointer++
+		continueRunning := d.execute(p)
+		p.operands = nil
+		return continueRunning && !p.faulted
+	}
 
-	handler, instructionFound := instructions[instruction]
-	if !instructionFound {
-		p.errors = append(p.errors,
-			fmt.Errorf("unknown instruction 0x%X at position 0x%X", instruction, p.instructionPointer-1))
+	// Instructions that fault while being fetched are fetched a byte at a time
+	instruction := p.fetchInstruction()
+	handler := instructions[instruction]
+	if handler == nil {
+		p.fault(ErrUnknownInstruction, fmt.Sprintf("0x%X", instruction), nil)
 		return false
 	}
 
 	continueRunning := handler(p)
-	return continueRunning && len(p.errors) == 0
+	return continueRunning && !p.faulted
+}
+
+// Stop makes Run return before the next instruction.  It is safe to
+// call from other goroutines, such as signal handlers.
+func (p *Processor) Stop() {
+	atomic.StoreInt32(&p.stopRequested, 1)
 }
 
 func (p *Processor) Run() int {
-	for continueRunning := true; continueRunning; continueRunning = p.Step() {
+	return p.RunContext(context.Background())
+}
+
+// RunContext executes instructions like Run, but stops with a LimitError
+// when ctx is done.
+func (p *Processor) RunContext(ctx context.Context) int {
+	done := ctx.Done()
+	for {
+		// Loading first avoids the cost of a swap on every instruction
+		if atomic.LoadInt32(&p.stopRequested) != 0 && atomic.CompareAndSwapInt32(&p.stopRequested, 1, 0) {
+			break
+		}
+		if done != nil {
+			select {
+			case <-done:
+				p.stopAtLimit(ctx.Err())
+				return p.reportErrors()
+			default:
+			}
+		}
+		if !p.Step() {
+			break
+		}
 	}
+	return p.reportErrors()
+}
+
+// reportErrors writes any execution errors and returns the exit status.
+// The messages hold the address of each error, and the symbols add its
+// source location.
+func (p *Processor) reportErrors() int {
 	if len(p.errors) > 0 {
 		fmt.Fprintf(p.errorWriter, "** ERRORS:\n")
 		for _, e := range p.errors {
-			fmt.Fprintf(p.errorWriter, "** %s\n", e)
+			if location := p.describeError(e); location != "" {
+				fmt.Fprintf(p.errorWriter, "** %s in %s\n", e, location)
+			} else {
+				fmt.Fprintf(p.errorWriter, "** %s\n", e)
+			}
 			p.errorWriter.Flush()
 		}
 		return 1
 	}
 	return 0
 }
+
+// describeError returns the source location of an execution error, or ""
+// if it is not known.
+func (p *Processor) describeError(err error) string {
+	if p.symbols == nil {
+		return ""
+	}
+	var fault *Fault
+	var limit *LimitError
+	if errors.As(err, &fault) {
+		return p.symbols.Describe(fault.InstructionPointer)
+	}
+	if errors.As(err, &limit) {
+		return p.symbols.Describe(limit.InstructionPointer)
+	}
+	return ""
+}
@@ -1,9 +1,13 @@
 package processor_test
 
 import (
+	"bufio"
 	"errors"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/scottmcleodjr/gebvm/memory"
 	"github.com/scottmcleodjr/gebvm/processor"
 )
 
@@ -70,3 +74,28 @@ func TestRun(t *testing.T) {
 		t.Errorf("got status %d, want 1", status)
 	}
 }
+
+type testSymbols map[uint16]string
+
+func (s testSymbols) Describe(address uint16) string {
+	return s[address]
+}
+
+func TestRunWithSymbols(t *testing.T) {
+	m := memory.New()
+	m.LoadProgram([]uint8{processor.Noop, 0x0F}) // Bad Instruction
+	var errorOutput strings.Builder
+	errorWriter := bufio.NewWriter(&errorOutput)
+	p := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		errorWriter,
+		processor.WithSymbols(testSymbols{0x0001: "test.asm:2 (start+0x1)"}),
+	)
+	p.Run()
+	expected := "** unknown instruction 0xF at position 0x1 in test.asm:2 (start+0x1)\n"
+	if !strings.HasSuffix(errorOutput.String(), expected) {
+		t.Errorf("got %q, want it to end with %q", errorOutput.String(), expected)
+	}
+}
@@ -0,0 +1,92 @@
+package processor
+
+import "fmt"
+
+// Protection restricts how instructions can use a region of memory.
+type Protection uint8
+
+const (
+	ReadOnly  Protection = 1 << iota // Instructions cannot write
+	NoExecute                        // Instructions cannot be fetched
+	StackOnly                        // Only stack operations can read or write, and nothing can be fetched
+)
+
+// Region applies a Protection to the addresses from Start to End, inclusive.
+type Region struct {
+	Start      uint16
+	End        uint16
+	Protection Protection
+}
+
+// WithProtection protects regions of memory.  Violations are reported as
+// faults for the offending instruction, and the access is not made.
+// When any region is StackOnly, stack operations outside StackOnly
+// regions are also violations.
+func WithProtection(regions ...Region) Option {
+	return func(p *Processor) {
+		if p.protection == nil {
+			p.protection = new([0xFFFF + 1]Protection)
+		}
+		for _, r := range regions {
+			for address := int(r.Start); address <= int(r.End); address++ {
+				p.protection[address] |= r.Protection
+			}
+			if r.Protection&StackOnly != 0 {
+				p.stackGuarded = true
+			}
+		}
+	}
+}
+
+// WithStackGuard makes the stack, from StackStart to StackLimit, StackOnly.
+func WithStackGuard() Option {
+	return WithProtection(Region{Start: StackStart, End: StackLimit, Protection: StackOnly})
+}
+
+// protectionFault records a fault unless the instruction already faulted,
+// so an access repeated by one instruction is only reported once.
+func (p *Processor) protectionFault(err error, address uint16) {
+	if !p.faulted {
+		p.fault(err, fmt.Sprintf("0x%04X", address), nil)
+	}
+}
+
+// canExecute checks that an instruction byte can be fetched from address.
+func (p *Processor) canExecute(address uint16) bool {
+	if p.protection == nil || p.protection[address]&(NoExecute|StackOnly) == 0 {
+		return true
+	}
+	p.protectionFault(ErrExecuteProtected, address)
+	return false
+}
+
+// canAccess checks that an instruction can read, or write if write is
+// true, address.  Stack operations must use stack regions when the stack
+// is guarded, and other instructions must not.
+func (p *Processor) canAccess(address uint16, write, stack bool) bool {
+	if p.protection == nil {
+		return true
+	}
+	protection := p.protection[address]
+	if write && protection&ReadOnly != 0 {
+		p.protectionFault(ErrWriteProtected, address)
+		return false
+	}
+	if stack && p.stackGuarded && protection&StackOnly == 0 {
+		p.protectionFault(ErrStackGuard, address)
+		return false
+	}
+	if !stack && protection&StackOnly != 0 {
+		p.protectionFault(ErrStackGuard, address)
+		return false
+	}
+	return true
+}
+
+// readMemory reads address for an instruction that is not a stack operation.
+func (p *Processor) readMemory(address uint16) uint8 {
+	if !p.canAccess(address, false, false) {
+		return 0x00
+	}
+	return p.memory.Read(address)
+}
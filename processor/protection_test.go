@@ -0,0 +1,90 @@
+package processor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func checkFault(t *testing.T, p *processor.Processor, kind error, ip uint16) {
+	t.Helper()
+	errs := p.Errors()
+	var fault *processor.Fault
+	if len(errs) != 1 || !errors.As(errs[0], &fault) || !errors.Is(fault, kind) {
+		t.Fatalf("got errors %v, want a single %v fault", errs, kind)
+	}
+	if fault.InstructionPointer != ip {
+		t.Errorf("got fault at 0x%04X, want 0x%04X", fault.InstructionPointer, ip)
+	}
+}
+
+func TestReadOnlyRegion(t *testing.T) {
+	program := []uint8{
+		processor.MoveLitReg, 0x00, R1, // 0x0000
+		processor.MoveLitReg, 0x00, R2, // 0x0003
+		processor.MoveLitMem, 0x99, R1, // 0x0006 Overwrites the program
+		processor.Halt,
+	}
+	p := newTestProcessorWithOptions(program,
+		processor.WithProtection(processor.Region{Start: 0x0000, End: 0x0009, Protection: processor.ReadOnly}))
+	if status := p.Run(); status != 1 {
+		t.Errorf("got status %d, want 1", status)
+	}
+	checkFault(t, p, processor.ErrWriteProtected, 0x0006)
+
+	p, m := newTestProcessorWithPogram(program)
+	p.Run()
+	if m.Read(0x0000) != 0x99 {
+		t.Errorf("got 0x%02X at 0x0000, want unprotected write of 0x99", m.Read(0x0000))
+	}
+}
+
+func TestNoExecuteRegion(t *testing.T) {
+	p := newTestProcessorWithOptions([]uint8{processor.Noop, processor.Jump, 0x10, 0x00},
+		processor.WithProtection(processor.Region{Start: 0x1000, End: 0x1FFF, Protection: processor.NoExecute}))
+	p.Run()
+	checkFault(t, p, processor.ErrExecuteProtected, 0x1000)
+}
+
+func TestStackGuard(t *testing.T) {
+	// Data instructions cannot use the stack
+	p := newTestProcessorWithOptions([]uint8{
+		processor.MoveLitReg, 0xFF, R1,
+		processor.MoveLitReg, 0x00, R2,
+		processor.MoveRegMem, R1, R1, // 0x0006
+		processor.Halt,
+	}, processor.WithStackGuard())
+	p.Run()
+	checkFault(t, p, processor.ErrStackGuard, 0x0006)
+
+	p = newTestProcessorWithOptions([]uint8{
+		processor.MoveLitReg, 0xFF, R1,
+		processor.MoveLitReg, 0x00, R2,
+		processor.MoveMemReg, R1, R3, // 0x0006
+		processor.Halt,
+	}, processor.WithStackGuard())
+	p.Run()
+	checkFault(t, p, processor.ErrStackGuard, 0x0006)
+
+	// Stack operations still work
+	p = newTestProcessorWithOptions([]uint8{
+		processor.StackPushLit, 0x42,
+		processor.StackPop, R1,
+		processor.Halt,
+	}, processor.WithStackGuard())
+	if status := p.Run(); status != 0 || p.RegisterValue(R1) != 0x42 {
+		t.Errorf("got status %d and R1 0x%02X, want 0 and 0x42", status, p.RegisterValue(R1))
+	}
+}
+
+func TestStackOperationsOutsideStackRegion(t *testing.T) {
+	// A smaller stack region makes pushes past its end fault
+	p := newTestProcessorWithOptions([]uint8{
+		processor.StackPushLit, 0x01,
+		processor.StackPushLit, 0x02, // 0x0002
+		processor.Halt,
+	}, processor.WithProtection(processor.Region{Start: 0xFF00, End: 0xFF00, Protection: processor.StackOnly}))
+	p.Run()
+	checkFault(t, p, processor.ErrStackGuard, 0x0002)
+}
@@ -0,0 +1,137 @@
+package processor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestExecuteNegate(t *testing.T) {
+	tests := []struct {
+		input, expected, flags uint8
+	}{
+		{input: 0x05, expected: 0xFB, flags: C | N},
+		{input: 0xFB, expected: 0x05, flags: C},
+		{input: 0x00, expected: 0x00, flags: Z},
+		{input: 0x80, expected: 0x80, flags: C | V | N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.input, R3,
+			processor.Negate, R3,
+		})
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R3) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%X and %s for 0x%X, want 0x%X and %s", p.RegisterValue(R3),
+				processor.FlagString(p.Flags()), test.input, test.expected, processor.FlagString(test.flags))
+		}
+	}
+}
+
+func TestSignedArithmetic(t *testing.T) {
+	tests := []struct {
+		instruction           uint8
+		inputLeft, inputRight uint8
+		expected              uint8
+		flags                 uint8
+	}{
+		{instruction: processor.SignedMultiply, inputLeft: 0xFD, inputRight: 0x04, expected: 0xF4, flags: N},         // -3 * 4
+		{instruction: processor.SignedMultiply, inputLeft: 0xFD, inputRight: 0xFC, expected: 0x0C, flags: 0},         // -3 * -4
+		{instruction: processor.SignedMultiply, inputLeft: 0x10, inputRight: 0x08, expected: 0x80, flags: C | V | N}, // 16 * 8
+		{instruction: processor.SignedMultiply, inputLeft: 0xF0, inputRight: 0x08, expected: 0x80, flags: N},         // -16 * 8
+		{instruction: processor.SignedDivide, inputLeft: 0xF9, inputRight: 0x02, expected: 0xFD, flags: N},           // -7 / 2
+		{instruction: processor.SignedDivide, inputLeft: 0xF9, inputRight: 0xFE, expected: 0x03, flags: 0},           // -7 / -2
+		{instruction: processor.SignedDivide, inputLeft: 0x80, inputRight: 0xFF, expected: 0x80, flags: V | N},       // -128 / -1
+		{instruction: processor.SignedRemainder, inputLeft: 0xF9, inputRight: 0x02, expected: 0xFF, flags: N},        // -7 rem 2
+		{instruction: processor.SignedRemainder, inputLeft: 0x07, inputRight: 0xFE, expected: 0x01, flags: 0},        // 7 rem -2
+		{instruction: processor.SignedRemainder, inputLeft: 0x80, inputRight: 0xFF, expected: 0x00, flags: Z},        // -128 rem -1
+		{instruction: processor.Modulo, inputLeft: 0xF9, inputRight: 0x02, expected: 0x01, flags: 0},                 // 249 mod 2
+		{instruction: processor.Modulo, inputLeft: 0x2A, inputRight: 0x07, expected: 0x00, flags: Z},                 // 42 mod 7
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.inputLeft, R1,
+			processor.MoveLitReg, test.inputRight, R2,
+			test.instruction, R1, R2,
+		})
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R0) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%X and %s for 0x%X with 0x%X and 0x%X, want 0x%X and %s",
+				p.RegisterValue(R0), processor.FlagString(p.Flags()), test.instruction,
+				test.inputLeft, test.inputRight, test.expected, processor.FlagString(test.flags))
+		}
+	}
+}
+
+func TestSignedDivideByZero(t *testing.T) {
+	for _, instruction := range []uint8{processor.SignedDivide, processor.SignedRemainder, processor.Modulo} {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, 0xF0, R1,
+			instruction, R1, R2,
+		})
+		p.Step()
+		stepAndCheckContinueValue(t, p, false)
+		if errs := p.Errors(); len(errs) != 1 || !errors.Is(errs[0], processor.ErrDivideByZero) {
+			t.Errorf("got errors %v for 0x%X, want a divide by zero error", errs, instruction)
+		}
+	}
+}
+
+func TestExecuteArithShiftRight(t *testing.T) {
+	tests := []struct {
+		input, distance, expected, flags uint8
+	}{
+		{input: 0xD6, distance: 1, expected: 0xEB, flags: N},
+		{input: 0xD6, distance: 2, expected: 0xF5, flags: C | N},
+		{input: 0x56, distance: 4, expected: 0x05, flags: 0},
+		{input: 0x80, distance: 8, expected: 0xFF, flags: C | N},
+		{input: 0x80, distance: 12, expected: 0xFF, flags: C | N},
+		{input: 0x7F, distance: 9, expected: 0x00, flags: Z},
+		{input: 0x81, distance: 0, expected: 0x81, flags: N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.input, R1,
+			processor.ArithShiftRight, R1, test.distance,
+		})
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if p.RegisterValue(R0) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%X and %s for 0x%X by %d, want 0x%X and %s", p.RegisterValue(R0),
+				processor.FlagString(p.Flags()), test.input, test.distance, test.expected,
+				processor.FlagString(test.flags))
+		}
+	}
+}
+
+func TestExecuteSignExtend(t *testing.T) {
+	tests := []struct {
+		input    uint8
+		expected uint16
+		flags    uint8
+	}{
+		{input: 0x05, expected: 0x0005, flags: 0},
+		{input: 0xFB, expected: 0xFFFB, flags: N},
+		{input: 0x00, expected: 0x0000, flags: Z},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitReg, test.input, R1,
+			processor.SignExtend, R1, R4,
+		})
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if registerPair(p, R4) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%04X and %s for 0x%X, want 0x%04X and %s", registerPair(p, R4),
+				processor.FlagString(p.Flags()), test.input, test.expected, processor.FlagString(test.flags))
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+const (
+	snapshotMagic   string = "GEBS"
+	snapshotVersion uint8  = 2
+	memorySize      int    = 0xFFFF + 1 // Limit for 16-bit addresses
+)
+
+// Snapshot is the complete state of a Processor and its memory.
+type Snapshot struct {
+	Registers          [RegisterCount]uint8
+	InstructionPointer uint16
+	StackPointer       uint16
+	StackSize          uint8
+	Flags              uint8
+	InterruptsEnabled  bool
+	PendingInterrupts  uint8   // bit per raised line
+	Errors             []error // errors encountered during execution
+	Memory             []uint8 // contents of every address
+}
+
+// Snapshot returns the current state of the Processor.  The memory is
+// read through the MemoryDevice, so devices with read side effects will
+// see a read of every address.
+func (p *Processor) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Registers:          p.registers,
+		InstructionPointer: p.instructionPointer,
+		StackPointer:       p.stackPointer,
+		StackSize:          p.stackSize,
+		Flags:              p.flags,
+		InterruptsEnabled:  p.interruptsEnabled,
+		PendingInterrupts:  uint8(atomic.LoadUint32(&p.pendingInterrupts)),
+		Errors:             make([]error, len(p.errors)),
+		Memory:             make([]uint8, memorySize),
+	}
+	copy(s.Errors, p.errors)
+	for address := range s.Memory {
+		s.Memory[address] = p.memory.Read(uint16(address))
+	}
+	return s
+}
+
+// Restore sets the Processor to the state in a Snapshot.  The memory is
+// restored with the MemoryDevice LoadProgram.
+func (p *Processor) Restore(s *Snapshot) error {
+	if len(s.Memory) != memorySize {
+		return fmt.Errorf("snapshot memory is %d bytes, want %d", len(s.Memory), memorySize)
+	}
+	if err := p.memory.LoadProgram(s.Memory); err != nil {
+		return err
+	}
+	p.InvalidateDecodeCache()
+	p.registers = s.Registers
+	p.instructionPointer = s.InstructionPointer
+	p.stackPointer = s.StackPointer
+	p.stackSize = s.StackSize
+	p.flags = s.Flags
+	p.interruptsEnabled = s.InterruptsEnabled
+	atomic.StoreUint32(&p.pendingInterrupts, uint32(s.PendingInterrupts))
+	p.errors = append([]error(nil), s.Errors...)
+	p.faulted = len(p.errors) > 0
+	return nil
+}
+
+// snapshotHeader is the fixed size part of the snapshot file format.
+// Multi-byte values are written high byte first, like addresses in
+// bytecode.  The header is followed by each error, as a snapshotError and
+// its operands, detail, cause and message, each a length (2 bytes) and
+// bytes, and then the memory.  Version 1 files wrote only the message of
+// each error.
+type snapshotHeader struct {
+	Magic              [4]uint8
+	Version            uint8
+	Registers          [RegisterCount]uint8
+	InstructionPointer uint16
+	StackPointer       uint16
+	StackSize          uint8
+	Flags              uint8
+	InterruptsEnabled  bool
+	PendingInterrupts  uint8
+	ErrorCount         uint16
+}
+
+// WriteTo writes the Snapshot in the snapshot file format.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	if len(s.Memory) != memorySize {
+		return 0, fmt.Errorf("snapshot memory is %d bytes, want %d", len(s.Memory), memorySize)
+	}
+	header := snapshotHeader{
+		Version:            snapshotVersion,
+		Registers:          s.Registers,
+		InstructionPointer: s.InstructionPointer,
+		StackPointer:       s.StackPointer,
+		StackSize:          s.StackSize,
+		Flags:              s.Flags,
+		InterruptsEnabled:  s.InterruptsEnabled,
+		PendingInterrupts:  s.PendingInterrupts,
+		ErrorCount:         uint16(len(s.Errors)),
+	}
+	copy(header.Magic[:], snapshotMagic)
+
+	cw := &countingWriter{writer: bufio.NewWriter(w)}
+	binary.Write(cw, binary.BigEndian, header)
+	for _, err := range s.Errors[:header.ErrorCount] {
+		writeSnapshotError(cw, err)
+	}
+	cw.Write(s.Memory)
+	if cw.err == nil {
+		cw.err = cw.writer.Flush()
+	}
+	return cw.count, cw.err
+}
+
+// ReadSnapshot reads a Snapshot in the snapshot file format.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	r = bufio.NewReader(r)
+	var header snapshotHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %s", err)
+	}
+	if string(header.Magic[:]) != snapshotMagic {
+		return nil, errors.New("not a snapshot file")
+	}
+	if header.Version != 1 && header.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	s := &Snapshot{
+		Registers:          header.Registers,
+		InstructionPointer: header.InstructionPointer,
+		StackPointer:       header.StackPointer,
+		StackSize:          header.StackSize,
+		Flags:              header.Flags,
+		InterruptsEnabled:  header.InterruptsEnabled,
+		PendingInterrupts:  header.PendingInterrupts,
+		Memory:             make([]uint8, memorySize),
+	}
+	for i := uint16(0); i < header.ErrorCount; i++ {
+		var saved savedError
+		var err error
+		if header.Version == 1 {
+			var message []uint8
+			message, err = readSnapshotString(r)
+			saved.message = string(message)
+		} else {
+			saved, err = readSnapshotError(r)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading snapshot: %s", err)
+		}
+		s.Errors = append(s.Errors, saved.restore())
+	}
+	if _, err := io.ReadFull(r, s.Memory); err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %s", err)
+	}
+	return s, nil
+}
+
+// snapshotErrorKinds are the kinds of error saved in snapshots, by
+// their code in the file.  Other errors are restored from their message.
+var snapshotErrorKinds = []error{
+	ErrInvalidRegister,
+	ErrUnknownInstruction,
+	ErrStackOverflow,
+	ErrStackUnderflow,
+	ErrIPOutOfBounds,
+	ErrDivideByZero,
+	ErrInput,
+	ErrWriteProtected,
+	ErrExecuteProtected,
+	ErrStackGuard,
+	ErrFrameBounds,
+	ErrStepLimit,
+	ErrOutOfFuel,
+	context.DeadlineExceeded,
+	context.Canceled,
+}
+
+// Types of snapshotError.
+const (
+	snapshotErrorMessage uint8 = iota
+	snapshotErrorFault
+	snapshotErrorLimit
+)
+
+// snapshotError is the fixed size part of an error in a snapshot file.
+// Fields that do not apply to the type are zero.
+type snapshotError struct {
+	Type               uint8
+	Kind               uint8 // index in snapshotErrorKinds
+	InstructionPointer uint16
+	Opcode             uint8
+	Steps              uint64
+}
+
+// snapshotErrorKind returns the code of kind, and false if it cannot be
+// saved.
+func snapshotErrorKind(kind error) (uint8, bool) {
+	for i, k := range snapshotErrorKinds {
+		if k == kind {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+func writeSnapshotError(cw *countingWriter, err error) {
+	var header snapshotError
+	var operands []uint8
+	var detail, cause, message string
+	var fault *Fault
+	var limit *LimitError
+	if errors.As(err, &fault) {
+		if kind, ok := snapshotErrorKind(fault.Err); ok {
+			header = snapshotError{
+				Type:               snapshotErrorFault,
+				Kind:               kind,
+				InstructionPointer: fault.InstructionPointer,
+				Opcode:             fault.Opcode,
+			}
+			operands, detail = fault.Operands, fault.Detail
+			if fault.Cause != nil {
+				cause = fault.Cause.Error()
+			}
+		}
+	} else if errors.As(err, &limit) {
+		if kind, ok := snapshotErrorKind(limit.Err); ok {
+			header = snapshotError{
+				Type:               snapshotErrorLimit,
+				Kind:               kind,
+				InstructionPointer: limit.InstructionPointer,
+				Steps:              limit.Steps,
+			}
+		}
+	}
+	if header.Type == snapshotErrorMessage {
+		message = err.Error()
+	}
+
+	binary.Write(cw, binary.BigEndian, header)
+	for _, field := range [][]uint8{operands, []uint8(detail), []uint8(cause), []uint8(message)} {
+		if len(field) > 0xFFFF {
+			field = field[:0xFFFF]
+		}
+		binary.Write(cw, binary.BigEndian, uint16(len(field)))
+		cw.Write(field)
+	}
+}
+
+// savedError is an error read from a snapshot file.
+type savedError struct {
+	header                 snapshotError
+	operands               []uint8
+	detail, cause, message string
+}
+
+// readSnapshotError reads an error written by writeSnapshotError.
+func readSnapshotError(r io.Reader) (savedError, error) {
+	var saved savedError
+	if err := binary.Read(r, binary.BigEndian, &saved.header); err != nil {
+		return saved, err
+	}
+	var fields [4][]uint8
+	for i := range fields {
+		field, err := readSnapshotString(r)
+		if err != nil {
+			return saved, err
+		}
+		fields[i] = field
+	}
+	saved.operands, saved.detail, saved.cause, saved.message = fields[0], string(fields[1]), string(fields[2]), string(fields[3])
+
+	switch saved.header.Type {
+	case snapshotErrorMessage:
+	case snapshotErrorFault, snapshotErrorLimit:
+		if int(saved.header.Kind) >= len(snapshotErrorKinds) {
+			return saved, fmt.Errorf("unknown error kind %d", saved.header.Kind)
+		}
+	default:
+		return saved, fmt.Errorf("unknown error type %d", saved.header.Type)
+	}
+	return saved, nil
+}
+
+// restore returns the execution error that was saved.
+func (e savedError) restore() error {
+	switch e.header.Type {
+	case snapshotErrorFault:
+		f := &Fault{
+			Err:                snapshotErrorKinds[e.header.Kind],
+			InstructionPointer: e.header.InstructionPointer,
+			Opcode:             e.header.Opcode,
+			Operands:           e.operands,
+			Detail:             e.detail,
+		}
+		if e.cause != "" {
+			f.Cause = errors.New(e.cause)
+		}
+		return f
+	case snapshotErrorLimit:
+		return &LimitError{
+			Err:                snapshotErrorKinds[e.header.Kind],
+			InstructionPointer: e.header.InstructionPointer,
+			Steps:              e.header.Steps,
+		}
+	}
+	return errors.New(e.message)
+}
+
+// readSnapshotString reads a length (2 bytes) and that many bytes.  It
+// returns nil for a length of 0.
+func readSnapshotString(r io.Reader) ([]uint8, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	value := make([]uint8, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// countingWriter counts bytes written and keeps the first error.
+type countingWriter struct {
+	writer *bufio.Writer
+	count  int64
+	err    error
+}
+
+func (c *countingWriter) Write(b []uint8) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.writer.Write(b)
+	c.count += int64(n)
+	c.err = err
+	return n, err
+}
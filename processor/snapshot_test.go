@@ -0,0 +1,133 @@
+package processor_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{
+		processor.MoveLitReg, 0x80, R1,
+		processor.Add, R1, R1, // Sets flags
+		processor.StackPushLit, 0x42,
+		processor.EnableInterrupts,
+		processor.Halt,
+	})
+	for i := 0; i < 4; i++ {
+		p.Step()
+	}
+	p.RaiseInterrupt(2)
+	m.Write(0x1234, 0xAB)
+	snapshot := p.Snapshot()
+
+	restored, restoredMemory := newTestProcessorWithPogram([]uint8{})
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Snapshot(), snapshot) {
+		t.Errorf("got %+v after restore, want %+v", restored.Snapshot(), snapshot)
+	}
+	if restored.RegisterValue(R0) != 0x00 || restored.RegisterValue(R1) != 0x80 ||
+		restored.InstructionPointer() != 0x0009 || restored.StackPointer() != 0xFF01 ||
+		restored.StackSize() != 1 || restored.Flags() != processor.FlagZero|processor.FlagCarry|processor.FlagOverflow ||
+		!restored.InterruptsEnabled() {
+		t.Errorf("got wrong state after restore: %+v", restored.Snapshot())
+	}
+	if restoredMemory.Read(0x1234) != 0xAB || restoredMemory.Read(0xFF00) != 0x42 {
+		t.Error("memory not restored")
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{0x0F}) // Bad instruction
+	p.Step()
+	restored, _ := newTestProcessorWithPogram([]uint8{})
+	restored.Restore(p.Snapshot())
+	if len(restored.Errors()) != 1 || restored.Errors()[0].Error() != p.Errors()[0].Error() {
+		t.Errorf("got errors %v after restore, want %v", restored.Errors(), p.Errors())
+	}
+}
+
+func TestSnapshotErrorKinds(t *testing.T) {
+	tests := []struct {
+		program []uint8
+		options []processor.Option
+		kind    error
+	}{
+		{program: []uint8{processor.Divide, R1, R2}, kind: processor.ErrDivideByZero},
+		{program: []uint8{processor.MoveRegReg, R1, 0x09}, kind: processor.ErrInvalidRegister},
+		{program: []uint8{processor.Jump, 0x00, 0x00}, options: []processor.Option{processor.WithStepLimit(3)},
+			kind: processor.ErrStepLimit},
+	}
+
+	for _, test := range tests {
+		p := newTestProcessorWithOptions(test.program, test.options...)
+		for p.Step() {
+		}
+		var file bytes.Buffer
+		if _, err := p.Snapshot().WriteTo(&file); err != nil {
+			t.Fatal(err)
+		}
+		read, err := processor.ReadSnapshot(&file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, _ := newTestProcessorWithPogram([]uint8{})
+		restored.Restore(read)
+
+		errs := restored.Errors()
+		if len(errs) != 1 || !errors.Is(errs[0], test.kind) || errs[0].Error() != p.Errors()[0].Error() {
+			t.Errorf("got errors %v after restore, want %v", errs, p.Errors())
+			continue
+		}
+		if status := processor.ExitStatus(errs); status != processor.ExitStatus(p.Errors()) {
+			t.Errorf("got exit status %d for %v after restore, want %d", status, errs[0], processor.ExitStatus(p.Errors()))
+		}
+		if !reflect.DeepEqual(errs[0], p.Errors()[0]) {
+			t.Errorf("got %#v after restore, want %#v", errs[0], p.Errors()[0])
+		}
+	}
+}
+
+func TestSnapshotFileFormat(t *testing.T) {
+	p, m := newTestProcessorWithPogram([]uint8{processor.MoveLitReg, 0x42, R3, 0x0F})
+	m.Write(0xFFFF, 0xCD)
+	p.Step()
+	p.Step()
+	snapshot := p.Snapshot()
+
+	var file bytes.Buffer
+	n, err := snapshot.WriteTo(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(file.Len()) {
+		t.Errorf("got %d bytes written, want %d", n, file.Len())
+	}
+	read, err := processor.ReadSnapshot(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, snapshot) {
+		t.Errorf("got %+v from file, want %+v", read, snapshot)
+	}
+
+	if _, err := processor.ReadSnapshot(bytes.NewReader([]uint8("not a snapshot at all"))); err == nil {
+		t.Error("got nil, want error reading invalid snapshot")
+	}
+}
+
+func TestStop(t *testing.T) {
+	p, _ := newTestProcessorWithPogram([]uint8{processor.Jump, 0x00, 0x00})
+	p.Stop()
+	if status := p.Run(); status != 0 {
+		t.Errorf("got status %d, want 0", status)
+	}
+	if p.InstructionPointer() != 0x0000 {
+		t.Errorf("got 0x%X at IP, want 0x0000", p.InstructionPointer())
+	}
+}
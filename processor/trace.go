@@ -0,0 +1,62 @@
+package processor
+
+// MemoryWrite is a single byte written to memory by an instruction.
+type MemoryWrite struct {
+	Address uint16
+	Value   uint8
+}
+
+// TraceRecord describes the execution of a single instruction.
+type TraceRecord struct {
+	InstructionPointer     uint16 // Address of the instruction
+	Opcode                 uint8
+	Mnemonic               string  // Empty for unknown instructions
+	Operands               []uint8 // Raw operand bytes
+	RegistersBefore        [RegisterCount]uint8
+	RegistersAfter         [RegisterCount]uint8
+	FlagsBefore            uint8
+	FlagsAfter             uint8
+	NextInstructionPointer uint16 // Address of the next instruction to execute
+	MemoryWrites           []MemoryWrite
+}
+
+// A Tracer receives a record of each instruction executed by a Processor.
+// The record is only valid for the duration of the call.
+type Tracer interface {
+	Trace(record *TraceRecord)
+}
+
+// WithTracer adds a Tracer to the Processor.  Tracing slows execution,
+// so records are only built when at least one Tracer is added.
+func WithTracer(t Tracer) Option {
+	return func(p *Processor) {
+		p.tracers = append(p.tracers, t)
+	}
+}
+
+func (p *Processor) tracedStep() bool {
+	record := &TraceRecord{
+		InstructionPointer: p.instructionPointer,
+		Opcode:             p.memory.Read(p.instructionPointer),
+		RegistersBefore:    p.registers,
+		FlagsBefore:        p.flags,
+	}
+	if info, found := LookupOpcode(record.Opcode); found {
+		record.Mnemonic = info.Mnemonic
+		for i := uint16(1); i < info.Size() && uint32(record.InstructionPointer)+uint32(i) <= 0xFFFF; i++ {
+			record.Operands = append(record.Operands, p.memory.Read(record.InstructionPointer+i))
+		}
+	}
+
+	p.trace = record
+	continueRunning := p.step()
+	p.trace = nil
+
+	record.RegistersAfter = p.registers
+	record.FlagsAfter = p.flags
+	record.NextInstructionPointer = p.instructionPointer
+	for _, t := range p.tracers {
+		t.Trace(record)
+	}
+	return continueRunning
+}
@@ -0,0 +1,86 @@
+package processor_test
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+type recordingTracer struct {
+	records []processor.TraceRecord
+}
+
+func (r *recordingTracer) Trace(record *processor.TraceRecord) {
+	r.records = append(r.records, *record)
+}
+
+func TestWithTracer(t *testing.T) {
+	m := memory.New()
+	m.LoadProgram([]uint8{
+		processor.MoveLitReg, 0x2A, R1, // 0x0000
+		processor.MoveLitReg, 0x01, R3, // 0x0003
+		processor.MoveRegMem, R1, R2, // 0x0006
+		processor.JumpEqual, R2, 0x00, 0x00, // 0x0009 (R0 == R2)
+	})
+	tracer := &recordingTracer{}
+	p := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+		processor.WithTracer(tracer),
+	)
+	for i := 0; i < 4; i++ {
+		p.Step()
+	}
+
+	if len(tracer.records) != 4 {
+		t.Fatalf("got %d records, want 4", len(tracer.records))
+	}
+	first := tracer.records[0]
+	if first.InstructionPointer != 0x0000 || first.Mnemonic != "MLR" ||
+		!bytes.Equal(first.Operands, []uint8{0x2A, R1}) || first.NextInstructionPointer != 0x0003 {
+		t.Errorf("got %+v for MLR record", first)
+	}
+	if first.RegistersBefore[R1] != 0x00 || first.RegistersAfter[R1] != 0x2A {
+		t.Errorf("got R1 0x%X before and 0x%X after, want 0x00 and 0x2A",
+			first.RegistersBefore[R1], first.RegistersAfter[R1])
+	}
+
+	store := tracer.records[2]
+	if len(store.MemoryWrites) != 1 || store.MemoryWrites[0] != (processor.MemoryWrite{Address: 0x0001, Value: 0x2A}) {
+		t.Errorf("got %+v for MRM memory writes, want 0x2A at 0x0001", store.MemoryWrites)
+	}
+
+	jump := tracer.records[3]
+	if jump.NextInstructionPointer != 0x0000 {
+		t.Errorf("got 0x%X for JEQ next IP, want 0x0000", jump.NextInstructionPointer)
+	}
+}
+
+func TestTraceFlags(t *testing.T) {
+	tracer := &recordingTracer{}
+	m := memory.New()
+	m.LoadProgram([]uint8{processor.Add, R0, R0})
+	p := processor.New(m, nil, nil, nil, processor.WithTracer(tracer))
+	p.Step()
+	if tracer.records[0].FlagsBefore != 0 || tracer.records[0].FlagsAfter != processor.FlagZero {
+		t.Errorf("got flags %s -> %s, want ---- -> Z---",
+			processor.FlagString(tracer.records[0].FlagsBefore), processor.FlagString(tracer.records[0].FlagsAfter))
+	}
+}
+
+func TestTraceUnknownInstruction(t *testing.T) {
+	tracer := &recordingTracer{}
+	m := memory.New()
+	m.LoadProgram([]uint8{0x0F})
+	p := processor.New(m, nil, nil, nil, processor.WithTracer(tracer))
+	stepAndCheckContinueValue(t, p, false)
+	if len(tracer.records) != 1 || tracer.records[0].Opcode != 0x0F || tracer.records[0].Mnemonic != "" {
+		t.Errorf("got %+v, want a record for unknown opcode 0x0F", tracer.records)
+	}
+}
@@ -0,0 +1,114 @@
+package processor
+
+import "fmt"
+
+// Word instructions treat a register pair as a 16-bit number, like a
+// pointer register: the register holds the high byte and the sequential
+// next register holds the low byte.  R7 cannot be used as a pair.
+
+// validRegisterPair faults and returns false if register does not start
+// a register pair.
+func (p *Processor) validRegisterPair(register uint8) bool {
+	if register >= RegisterCount-1 {
+		invalid := register
+		if register == RegisterCount-1 {
+			invalid = register + 1
+		}
+		p.fault(ErrInvalidRegister, fmt.Sprintf("R%d", invalid), nil)
+		return false
+	}
+	return true
+}
+
+// setRegisterPair stores value in a register pair.  Nothing is stored if
+// either register is invalid.
+func (p *Processor) setRegisterPair(register uint8, value uint16) {
+	if !p.validRegisterPair(register) {
+		return
+	}
+	p.registers[register] = uint8(value >> 8)
+	p.registers[register+1] = uint8(value)
+}
+
+// storeResultPair stores the result of a word instruction in R0 and R1,
+// or in the destination pair of a form that names one.
+func (p *Processor) storeResultPair(value uint16) {
+	register := uint8(0)
+	if p.namedDestination {
+		register = p.fetchInstruction()
+	}
+	p.setRegisterPair(register, value)
+}
+
+func (p *Processor) executeMoveLitWord() bool {
+	literal := p.fetchAddressInstruction()
+	register := p.fetchInstruction()
+	p.setRegisterPair(register, literal)
+	return true
+}
+
+func (p *Processor) executeIncWord() bool {
+	register := p.fetchInstruction()
+	if !p.validRegisterPair(register) {
+		return false
+	}
+	value := p.registerPointerValue(register)
+	p.setRegisterPair(register, value+1)
+	// Carry is unchanged, like INC
+	p.setWordFlags(value+1, p.flagSet(FlagCarry), value == 0x7FFF)
+	return true
+}
+
+func (p *Processor) executeDecWord() bool {
+	register := p.fetchInstruction()
+	if !p.validRegisterPair(register) {
+		return false
+	}
+	value := p.registerPointerValue(register)
+	p.setRegisterPair(register, value-1)
+	// Carry is unchanged, like DEC
+	p.setWordFlags(value-1, p.flagSet(FlagCarry), value == 0x8000)
+	return true
+}
+
+func (p *Processor) executeAddWord() bool {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	left := p.registerPointerValue(registerLeft)
+	right := p.registerPointerValue(registerRight)
+	sum := left + right
+	p.storeResultPair(sum)
+	p.setWordFlags(sum, uint32(left)+uint32(right) > 0xFFFF, (left^sum)&(right^sum)&0x8000 != 0)
+	return true
+}
+
+func (p *Processor) executeSubtractWord() bool {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	diff := p.subtractWord(p.registerPointerValue(registerLeft), p.registerPointerValue(registerRight))
+	p.storeResultPair(diff)
+	return true
+}
+
+func (p *Processor) executeCompareWord() bool {
+	registerLeft := p.fetchInstruction()
+	registerRight := p.fetchInstruction()
+	p.subtractWord(p.registerPointerValue(registerLeft), p.registerPointerValue(registerRight))
+	return true
+}
+
+// subtractWord returns left - right and sets the flags for the result.
+func (p *Processor) subtractWord(left, right uint16) uint16 {
+	diff := left - right
+	p.setWordFlags(diff, left < right, (left^right)&(left^diff)&0x8000 != 0)
+	return diff
+}
+
+func (p *Processor) executeSignExtend() bool {
+	register := p.fetchInstruction()
+	pairRegister := p.fetchInstruction()
+	value := uint16(int16(int8(p.RegisterValue(register))))
+	p.setRegisterPair(pairRegister, value)
+	p.setWordFlags(value, false, false)
+	return true
+}
@@ -0,0 +1,108 @@
+package processor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func registerPair(p *processor.Processor, register uint8) uint16 {
+	return uint16(p.RegisterValue(register))<<8 + uint16(p.RegisterValue(register+1))
+}
+
+func TestExecuteMoveLitWord(t *testing.T) {
+	for register := R0; register < processor.RegisterCount-1; register++ {
+		p, _ := newTestProcessorWithPogram([]uint8{processor.MoveLitWord, 0x12, 0x34, register})
+		stepAndCheckContinueValue(t, p, true)
+		if registerPair(p, register) != 0x1234 {
+			t.Errorf("got 0x%04X at R%d, want 0x1234", registerPair(p, register), register)
+		}
+	}
+}
+
+func TestExecuteIncDecWord(t *testing.T) {
+	tests := []struct {
+		instruction uint8
+		input       uint16
+		expected    uint16
+		flags       uint8
+	}{
+		{instruction: processor.IncWord, input: 0x00FF, expected: 0x0100, flags: 0},
+		{instruction: processor.IncWord, input: 0x7FFF, expected: 0x8000, flags: V | N},
+		{instruction: processor.IncWord, input: 0xFFFF, expected: 0x0000, flags: Z},
+		{instruction: processor.DecWord, input: 0x0100, expected: 0x00FF, flags: 0},
+		{instruction: processor.DecWord, input: 0x8000, expected: 0x7FFF, flags: V},
+		{instruction: processor.DecWord, input: 0x0000, expected: 0xFFFF, flags: N},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitWord, highByte(test.input), lowByte(test.input), R4,
+			test.instruction, R4,
+		})
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if registerPair(p, R4) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%04X and %s for 0x%X with 0x%04X, want 0x%04X and %s",
+				registerPair(p, R4), processor.FlagString(p.Flags()), test.instruction, test.input,
+				test.expected, processor.FlagString(test.flags))
+		}
+	}
+}
+
+func TestWordArithmetic(t *testing.T) {
+	tests := []struct {
+		instruction           uint8
+		inputLeft, inputRight uint16
+		expected              uint16
+		flags                 uint8
+	}{
+		{instruction: processor.AddWord, inputLeft: 0x12FF, inputRight: 0x0101, expected: 0x1400, flags: 0},
+		{instruction: processor.AddWord, inputLeft: 0xFFFF, inputRight: 0x0001, expected: 0x0000, flags: Z | C},
+		{instruction: processor.AddWord, inputLeft: 0x7FFF, inputRight: 0x0001, expected: 0x8000, flags: V | N},
+		{instruction: processor.SubtractWord, inputLeft: 0x1400, inputRight: 0x0101, expected: 0x12FF, flags: 0},
+		{instruction: processor.SubtractWord, inputLeft: 0x0100, inputRight: 0x0200, expected: 0xFF00, flags: C | N},
+		{instruction: processor.SubtractWord, inputLeft: 0x8000, inputRight: 0x0001, expected: 0x7FFF, flags: V},
+		{instruction: processor.CompareWord, inputLeft: 0x1234, inputRight: 0x1234, expected: 0xAAAA, flags: Z},
+		{instruction: processor.CompareWord, inputLeft: 0x0100, inputRight: 0x00FF, expected: 0xAAAA, flags: 0},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram([]uint8{
+			processor.MoveLitWord, 0xAA, 0xAA, R0,
+			processor.MoveLitWord, highByte(test.inputLeft), lowByte(test.inputLeft), R2,
+			processor.MoveLitWord, highByte(test.inputRight), lowByte(test.inputRight), R4,
+			test.instruction, R2, R4,
+		})
+		p.Step()
+		p.Step()
+		p.Step()
+		stepAndCheckContinueValue(t, p, true)
+		if registerPair(p, R0) != test.expected || p.Flags() != test.flags {
+			t.Errorf("got 0x%04X and %s for 0x%X with 0x%04X and 0x%04X, want 0x%04X and %s",
+				registerPair(p, R0), processor.FlagString(p.Flags()), test.instruction,
+				test.inputLeft, test.inputRight, test.expected, processor.FlagString(test.flags))
+		}
+	}
+}
+
+func TestWordInvalidRegister(t *testing.T) {
+	tests := [][]uint8{
+		{processor.MoveLitWord, 0x12, 0x34, R7},
+		{processor.IncWord, R7},
+		{processor.DecWord, R7},
+	}
+
+	for _, test := range tests {
+		p, _ := newTestProcessorWithPogram(append([]uint8{processor.MoveLitReg, 0x42, R7}, test...))
+		p.Step()
+		stepAndCheckContinueValue(t, p, false)
+		if errs := p.Errors(); len(errs) != 1 || !errors.Is(errs[0], processor.ErrInvalidRegister) {
+			t.Errorf("got errors %v for 0x%X, want one invalid register error", errs, test[0])
+		}
+		if p.RegisterValue(R7) != 0x42 {
+			t.Errorf("got 0x%X at R7 for 0x%X, want it unchanged", p.RegisterValue(R7), test[0])
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// Field numbers from the pprof profile.proto messages.
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileMapping     = 3
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+	profilePeriodType  = 11
+	profilePeriod      = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	mappingID              = 1
+	mappingMemoryStart     = 2
+	mappingMemoryLimit     = 3
+	mappingFilename        = 5
+	mappingHasFunctions    = 7
+	mappingHasFilenames    = 8
+	mappingHasLineNumbers  = 9
+	mappingHasInlineFrames = 10
+
+	locationID        = 1
+	locationMappingID = 2
+	locationAddress   = 3
+	locationLine      = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// protoBuffer encodes protocol buffer fields.
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.WriteByte(uint8(x) | 0x80)
+		x >>= 7
+	}
+	b.WriteByte(uint8(x))
+}
+
+func (b *protoBuffer) key(field, wireType int) {
+	b.varint(uint64(field<<3 | wireType))
+}
+
+func (b *protoBuffer) uint64Field(field int, x uint64) {
+	if x != 0 {
+		b.key(field, 0)
+		b.varint(x)
+	}
+}
+
+func (b *protoBuffer) boolField(field int, x bool) {
+	if x {
+		b.uint64Field(field, 1)
+	}
+}
+
+func (b *protoBuffer) bytesField(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *protoBuffer) packedField(field int, values []uint64) {
+	var packed protoBuffer
+	for _, x := range values {
+		packed.varint(x)
+	}
+	b.bytesField(field, packed.Bytes())
+}
+
+func (b *protoBuffer) messageField(field int, encode func(*protoBuffer)) {
+	var message protoBuffer
+	encode(&message)
+	b.bytesField(field, message.Bytes())
+}
+
+// pprofBuilder collects the functions, locations and strings of a profile.
+type pprofBuilder struct {
+	profiler  *Profiler
+	strings   []string
+	stringIDs map[string]uint64
+	functions map[uint16]uint64    // Function ID for each function address
+	locations map[[2]uint16]uint64 // Location ID for each address and function
+	encoded   protoBuffer          // Function and location messages
+}
+
+func (b *pprofBuilder) stringID(s string) uint64 {
+	id, found := b.stringIDs[s]
+	if !found {
+		id = uint64(len(b.strings))
+		b.strings = append(b.strings, s)
+		b.stringIDs[s] = id
+	}
+	return id
+}
+
+func (b *pprofBuilder) functionID(address uint16) uint64 {
+	id, found := b.functions[address]
+	if found {
+		return id
+	}
+	id = uint64(len(b.functions) + 1)
+	b.functions[address] = id
+
+	name := b.stringID(b.profiler.functionName(address))
+	filename, line := b.sourceLine(address)
+	b.encoded.messageField(profileFunction, func(m *protoBuffer) {
+		m.uint64Field(functionID, id)
+		m.uint64Field(functionName, name)
+		m.uint64Field(functionSystemName, name)
+		m.uint64Field(functionFilename, filename)
+		m.uint64Field(functionStartLine, line)
+	})
+	return id
+}
+
+func (b *pprofBuilder) locationID(address, function uint16) uint64 {
+	key := [2]uint16{address, function}
+	id, found := b.locations[key]
+	if found {
+		return id
+	}
+	id = uint64(len(b.locations) + 1)
+	b.locations[key] = id
+
+	fn := b.functionID(function)
+	_, line := b.sourceLine(address)
+	b.encoded.messageField(profileLocation, func(m *protoBuffer) {
+		m.uint64Field(locationID, id)
+		m.uint64Field(locationMappingID, 1)
+		m.uint64Field(locationAddress, uint64(address))
+		m.messageField(locationLine, func(l *protoBuffer) {
+			l.uint64Field(lineFunctionID, fn)
+			l.uint64Field(lineLine, line)
+		})
+	})
+	return id
+}
+
+// sourceLine returns the string ID of the source file and the line for
+// address, or zeros if there are no symbols for it.
+func (b *pprofBuilder) sourceLine(address uint16) (uint64, uint64) {
+	if b.profiler.symbols == nil {
+		return 0, 0
+	}
+	line, found := b.profiler.symbols.Location(address)
+	if !found {
+		return 0, 0
+	}
+	return b.stringID(line.File), uint64(line.Line)
+}
+
+// WritePprof writes the profile as a gzipped pprof protocol buffer, which
+// can be opened with go tool pprof.  Each sample is the number of
+// instructions executed at an address with a call stack.  program names
+// the profiled program.
+func (p *Profiler) WritePprof(w io.Writer, program string) error {
+	b := &pprofBuilder{
+		profiler:  p,
+		stringIDs: map[string]uint64{},
+		functions: map[uint16]uint64{},
+		locations: map[[2]uint16]uint64{},
+	}
+	b.stringID("") // The string table starts with the empty string
+
+	var out protoBuffer
+	instructions, count := b.stringID("instructions"), b.stringID("count")
+	valueType := func(m *protoBuffer) {
+		m.uint64Field(valueTypeType, instructions)
+		m.uint64Field(valueTypeUnit, count)
+	}
+	out.messageField(profileSampleType, valueType)
+
+	samples := make([]*sample, 0, len(p.samples))
+	for _, s := range p.samples {
+		samples = append(samples, s)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].count > samples[j].count })
+	for _, s := range samples {
+		// Locations are listed from the leaf to the root
+		ids := []uint64{b.locationID(s.address, s.frames[len(s.frames)-1].function)}
+		for i := len(s.frames) - 1; i > 0; i-- {
+			ids = append(ids, b.locationID(s.frames[i].callSite, s.frames[i-1].function))
+		}
+		out.messageField(profileSample, func(m *protoBuffer) {
+			m.packedField(sampleLocationID, ids)
+			m.packedField(sampleValue, []uint64{s.count})
+		})
+	}
+
+	filename := b.stringID(program)
+	out.messageField(profileMapping, func(m *protoBuffer) {
+		m.uint64Field(mappingID, 1)
+		m.uint64Field(mappingMemoryStart, 0x0000)
+		m.uint64Field(mappingMemoryLimit, 0xFFFF+1)
+		m.uint64Field(mappingFilename, filename)
+		m.boolField(mappingHasFunctions, true)
+		m.boolField(mappingHasFilenames, p.symbols != nil)
+		m.boolField(mappingHasLineNumbers, p.symbols != nil)
+		m.boolField(mappingHasInlineFrames, true)
+	})
+	out.Write(b.encoded.Bytes())
+	for _, s := range b.strings {
+		out.bytesField(profileStringTable, []byte(s))
+	}
+	out.messageField(profilePeriodType, valueType)
+	out.uint64Field(profilePeriod, 1)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,266 @@
+// Package profile counts where a program spends its instructions.
+//
+// A Profiler is a processor.Tracer.  It counts each executed address and
+// opcode, and the inclusive and exclusive instruction counts of each
+// function, where a function is the target of a CLL or an interrupt
+// handler.  Results can be written as a sorted text report or as a Go
+// pprof profile.
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/scottmcleodjr/gebvm/disassembler"
+	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/symbols"
+)
+
+// reportAddresses is the number of hot addresses in a report.
+const reportAddresses = 20
+
+// frame is a function on the call stack and the address it was called
+// from.  The first frame has no call site.
+type frame struct {
+	function uint16
+	callSite uint16
+}
+
+// sample counts the instructions executed at an address with a call stack.
+type sample struct {
+	frames  []frame
+	address uint16
+	count   uint64
+}
+
+type sampleKey struct {
+	stack   string
+	address uint16
+}
+
+// Profiler is a processor.Tracer that counts executed instructions.
+type Profiler struct {
+	symbols      *symbols.Table
+	total        uint64
+	addresses    [0xFFFF + 1]uint64
+	opcodes      [0xFF + 1]uint64
+	instructions map[uint16]string // Text of each executed address
+	calls        map[uint16]uint64
+	samples      map[sampleKey]*sample
+	frames       []frame
+	stackKey     string
+	next         uint16 // Expected address of the next instruction
+}
+
+// New returns a Profiler.  table provides function names and source
+// lines, and can be nil.
+func New(table *symbols.Table) *Profiler {
+	return &Profiler{
+		symbols:      table,
+		instructions: map[uint16]string{},
+		calls:        map[uint16]uint64{},
+		samples:      map[sampleKey]*sample{},
+	}
+}
+
+// Trace counts a record.
+func (p *Profiler) Trace(record *processor.TraceRecord) {
+	address := record.InstructionPointer
+	if len(p.frames) == 0 {
+		p.push(frame{function: address})
+	} else if address != p.next {
+		// Execution continued somewhere unexpected, such as an interrupt handler
+		p.push(frame{function: address, callSite: p.next})
+	}
+	p.next = record.NextInstructionPointer
+
+	p.total++
+	p.addresses[address]++
+	p.opcodes[record.Opcode]++
+	if _, found := p.instructions[address]; !found {
+		p.instructions[address] = p.instructionText(record)
+	}
+	key := sampleKey{stack: p.stackKey, address: address}
+	s, found := p.samples[key]
+	if !found {
+		s = &sample{frames: append([]frame{}, p.frames...), address: address}
+		p.samples[key] = s
+	}
+	s.count++
+
+	info, found := processor.LookupOpcode(record.Opcode)
+	if !found {
+		return
+	}
+	switch info.Flow {
+	case processor.FlowCall:
+		p.push(frame{function: record.NextInstructionPointer, callSite: address})
+	case processor.FlowReturn:
+		if len(p.frames) > 1 {
+			p.frames = p.frames[:len(p.frames)-1]
+			p.updateStackKey()
+		}
+	}
+}
+
+func (p *Profiler) push(f frame) {
+	p.frames = append(p.frames, f)
+	p.calls[f.function]++
+	p.updateStackKey()
+}
+
+func (p *Profiler) updateStackKey() {
+	var b strings.Builder
+	for _, f := range p.frames {
+		b.WriteString(string([]byte{
+			uint8(f.function >> 8), uint8(f.function), uint8(f.callSite >> 8), uint8(f.callSite),
+		}))
+	}
+	p.stackKey = b.String()
+}
+
+func (p *Profiler) instructionText(record *processor.TraceRecord) string {
+	bytes := append([]uint8{record.Opcode}, record.Operands...)
+	line := disassembler.Linear(bytes, record.InstructionPointer)[0]
+	if line.Instruction == nil {
+		return fmt.Sprintf(".byte 0x%02X", record.Opcode)
+	}
+	if p.symbols != nil {
+		return line.TextWithLabels(p.symbols)
+	}
+	return line.Text()
+}
+
+// Total returns the number of instructions counted.
+func (p *Profiler) Total() uint64 {
+	return p.total
+}
+
+// AddressCount returns the number of times the instruction at address
+// was executed.
+func (p *Profiler) AddressCount(address uint16) uint64 {
+	return p.addresses[address]
+}
+
+// OpcodeCount returns the number of instructions executed with opcode.
+func (p *Profiler) OpcodeCount(opcode uint8) uint64 {
+	return p.opcodes[opcode]
+}
+
+// Function holds the instruction counts of a function.
+type Function struct {
+	Address   uint16 // Entry address
+	Name      string
+	Calls     uint64 // Times the function was entered
+	Inclusive uint64 // Instructions executed in the function and the functions it called
+	Exclusive uint64 // Instructions executed in the function itself
+}
+
+// Functions returns the counts for each function, sorted by inclusive
+// count.  The first function entered, at the start of the profile, is
+// included.
+func (p *Profiler) Functions() []Function {
+	functions := map[uint16]*Function{}
+	get := func(address uint16) *Function {
+		f, found := functions[address]
+		if !found {
+			f = &Function{Address: address, Name: p.functionName(address), Calls: p.calls[address]}
+			functions[address] = f
+		}
+		return f
+	}
+	for _, s := range p.samples {
+		seen := map[uint16]bool{}
+		for _, fr := range s.frames {
+			if !seen[fr.function] {
+				seen[fr.function] = true
+				get(fr.function).Inclusive += s.count
+			}
+		}
+		get(s.frames[len(s.frames)-1].function).Exclusive += s.count
+	}
+
+	out := make([]Function, 0, len(functions))
+	for _, f := range functions {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Inclusive != out[j].Inclusive {
+			return out[i].Inclusive > out[j].Inclusive
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+// functionName returns the label for a function, or its address.
+func (p *Profiler) functionName(address uint16) string {
+	if p.symbols != nil {
+		if label, found := p.symbols.LabelAt(address); found {
+			return label
+		}
+	}
+	return fmt.Sprintf("0x%04X", address)
+}
+
+func percent(count, total uint64) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", 100*float64(count)/float64(total))
+}
+
+// WriteReport writes the hottest addresses, the opcode counts and the
+// function counts, each sorted by count.
+func (p *Profiler) WriteReport(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	addresses := make([]uint16, 0, len(p.instructions))
+	for address := range p.instructions {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if p.addresses[addresses[i]] != p.addresses[addresses[j]] {
+			return p.addresses[addresses[i]] > p.addresses[addresses[j]]
+		}
+		return addresses[i] < addresses[j]
+	})
+	if len(addresses) > reportAddresses {
+		addresses = addresses[:reportAddresses]
+	}
+	fmt.Fprintf(b, "Hot addresses (%d instructions executed)\n", p.total)
+	fmt.Fprintf(b, "%12s %8s  %-7s  %s\n", "count", "%", "address", "instruction")
+	for _, address := range addresses {
+		count := p.addresses[address]
+		fmt.Fprintf(b, "%12d %8s  0x%04X   %s\n", count, percent(count, p.total), address, p.instructions[address])
+	}
+
+	var opcodes []int
+	for opcode, count := range p.opcodes {
+		if count > 0 {
+			opcodes = append(opcodes, opcode)
+		}
+	}
+	sort.SliceStable(opcodes, func(i, j int) bool { return p.opcodes[opcodes[i]] > p.opcodes[opcodes[j]] })
+	fmt.Fprintf(b, "\nOpcodes\n")
+	fmt.Fprintf(b, "%12s %8s  %-7s  %s\n", "count", "%", "opcode", "mnemonic")
+	for _, opcode := range opcodes {
+		mnemonic := "(unknown)"
+		if info, found := processor.LookupOpcode(uint8(opcode)); found {
+			mnemonic = info.Mnemonic
+		}
+		count := p.opcodes[opcode]
+		fmt.Fprintf(b, "%12d %8s  0x%02X     %s\n", count, percent(count, p.total), opcode, mnemonic)
+	}
+
+	fmt.Fprintf(b, "\nFunctions\n")
+	fmt.Fprintf(b, "%12s %8s %12s %8s %8s  %s\n", "inclusive", "%", "exclusive", "%", "calls", "function")
+	for _, f := range p.Functions() {
+		fmt.Fprintf(b, "%12d %8s %12d %8s %8d  %s\n", f.Inclusive, percent(f.Inclusive, p.total),
+			f.Exclusive, percent(f.Exclusive, p.total), f.Calls, f.Name)
+	}
+	return b.Flush()
+}
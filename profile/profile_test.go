@@ -0,0 +1,178 @@
+package profile_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/assembler"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/profile"
+)
+
+const testSource = `
+start:  MLR 3, R2
+again:  CLL work
+        DEC R2
+        JNE R2, again
+        HLT
+work:   CLL tiny
+        RET
+tiny:   NOP
+        RET
+`
+
+func runProfiled(t *testing.T) *profile.Profiler {
+	t.Helper()
+	program, err := assembler.Assemble("test.asm", []byte(testSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := memory.New()
+	m.LoadProgram(program.Image)
+	profiler := profile.New(program.Symbols)
+	p := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+		processor.WithTracer(profiler),
+	)
+	if status := p.Run(); status != 0 {
+		t.Fatalf("got status %d, want 0", status)
+	}
+	return profiler
+}
+
+func TestCounts(t *testing.T) {
+	profiler := runProfiled(t)
+	// MLR, then 3 times CLL work, CLL tiny, NOP, RET, RET, DEC, JNE, then HLT
+	if profiler.Total() != 23 {
+		t.Errorf("got total %d, want 23", profiler.Total())
+	}
+	if count := profiler.AddressCount(0x0003); count != 3 {
+		t.Errorf("got %d at 0x0003, want 3", count)
+	}
+	if count := profiler.OpcodeCount(processor.Return); count != 6 {
+		t.Errorf("got %d RET, want 6", count)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	expected := []profile.Function{
+		{Address: 0x0000, Name: "start", Calls: 1, Inclusive: 23, Exclusive: 11},
+		{Address: 0x000D, Name: "work", Calls: 3, Inclusive: 12, Exclusive: 6},
+		{Address: 0x0011, Name: "tiny", Calls: 3, Inclusive: 6, Exclusive: 6},
+	}
+	functions := runProfiled(t).Functions()
+	if len(functions) != len(expected) {
+		t.Fatalf("got %+v, want %+v", functions, expected)
+	}
+	for i := range expected {
+		if functions[i] != expected[i] {
+			t.Errorf("got %+v, want %+v", functions[i], expected[i])
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var report strings.Builder
+	if err := runProfiled(t).WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Hot addresses (23 instructions executed)",
+		"           3   13.04%  0x0003   CLL work\n",
+		"           6   26.09%  0x84     RET\n",
+		"          12   52.17%            6   26.09%        3  work\n",
+	} {
+		if !strings.Contains(report.String(), expected) {
+			t.Errorf("got report\n%s\nwant it to contain %q", report.String(), expected)
+		}
+	}
+}
+
+// protoFields returns the length delimited fields of a protocol buffer
+// message with the field number, and the sum of the varint fields.
+func protoFields(t *testing.T, data []byte, field int) ([][]byte, uint64) {
+	t.Helper()
+	var out [][]byte
+	var sum uint64
+	varint := func() uint64 {
+		var x uint64
+		for shift := uint(0); ; shift += 7 {
+			b := data[0]
+			data = data[1:]
+			x |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				return x
+			}
+		}
+	}
+	for len(data) > 0 {
+		key := varint()
+		switch key & 7 {
+		case 0:
+			value := varint()
+			if int(key>>3) == field {
+				sum += value
+			}
+		case 2:
+			length := varint()
+			if int(key>>3) == field {
+				out = append(out, data[:length])
+			}
+			data = data[length:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return out, sum
+}
+
+func TestWritePprof(t *testing.T) {
+	var out bytes.Buffer
+	if err := runProfiled(t).WritePprof(&out, "test.geb"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stringTable, _ := protoFields(t, data, 6)
+	strs := map[string]bool{}
+	for _, s := range stringTable {
+		strs[string(s)] = true
+	}
+	if len(stringTable) == 0 || len(stringTable[0]) != 0 {
+		t.Error("got string table without an empty first string")
+	}
+	for _, name := range []string{"instructions", "count", "start", "work", "tiny", "test.asm", "test.geb"} {
+		if !strs[name] {
+			t.Errorf("got string table without %q", name)
+		}
+	}
+
+	samples, _ := protoFields(t, data, 2)
+	var total uint64
+	for _, s := range samples {
+		values, _ := protoFields(t, s, 2)
+		for _, packed := range values {
+			_, sum := protoFields(t, append([]byte{0x08}, packed...), 1)
+			total += sum
+		}
+	}
+	if total != 23 {
+		t.Errorf("got sample total %d, want 23", total)
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/scottmcleodjr/gebvm/coverage"
+	"github.com/scottmcleodjr/gebvm/executable"
+	"github.com/scottmcleodjr/gebvm/memory"
+	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/profile"
+	"github.com/scottmcleodjr/gebvm/symbols"
+	"github.com/scottmcleodjr/gebvm/trace"
+)
+
+const (
+	resumeHelpText string = `Provide the snapshot file to resume as an argument.  The run options
+can be given before the file.
+
+  Example: ./gebvm resume --snapshot checkpoint.snap checkpoint.snap
+
+`
+)
+
+// runOptions holds the flags shared by the run and resume commands.
+type runOptions struct {
+	flags        *flag.FlagSet
+	traceFormat  *string
+	traceFile    *string
+	snapshotFile *string
+	snapshotOn   *string
+	symbolsFile  *string
+	maxSteps     *uint64
+	timeout      *time.Duration
+	protect      regionList
+	stackGuard   *bool
+	profile      *bool
+	pprofFile    *string
+	coverageFile *string
+	coverageFmt  *string
+	banks        bankWindow
+	bankSelect   *string
+	bankImage    *string
+}
+
+func newRunOptions(name string) *runOptions {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	opts := &runOptions{
+		flags:        flags,
+		traceFormat:  flags.String("trace", "", "write a record of each executed instruction as `text` or json"),
+		traceFile:    flags.String("trace-file", "", "write trace records to `file` instead of stderr"),
+		snapshotFile: flags.String("snapshot", "", "write a machine snapshot to `file` when execution stops"),
+		snapshotOn:   flags.String("snapshot-on", "halt,signal,error", "comma separated `events` that write a snapshot"),
+		maxSteps:     flags.Uint64("max-steps", 0, "stop with an error after `n` instructions (default: no limit)"),
+		timeout:      flags.Duration("timeout", 0, "stop with an error after `duration`, such as 5s (default: no limit)"),
+		symbolsFile:  flags.String("symbols", "", "read debug symbols from `file` (default: the bytecode file with a .sym extension)"),
+		profile:      flags.Bool("profile", false, "write a report of the most executed addresses, opcodes and functions to stderr"),
+		pprofFile:    flags.String("pprof", "", "write a profile for go tool pprof to `file`"),
+		coverageFile: flags.String("coverage", "", "write a coverage report of executed instructions and branches to `file`"),
+		coverageFmt:  flags.String("coverage-format", "lcov", "coverage report `format`, lcov or html"),
+		stackGuard:   flags.Bool("stack-guard", false, "only allow stack operations to use the stack and keep them there"),
+		bankSelect:   flags.String("bank-select", "", "map the bank-select port to `address`"),
+		bankImage:    flags.String("bank-image", "", "load `file` across the banks, starting at bank 0"),
+	}
+	flags.Var(&opts.protect, "protect", "protect `start-end:kinds`, where kinds are ro, nx and stack separated by commas (repeatable)")
+	flags.Var(&opts.banks, "banks", "map `start-end:count` banks of RAM to a window")
+	return opts
+}
+
+// regionList is a flag.Value for repeated --protect options.
+type regionList []processor.Region
+
+func (l *regionList) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *regionList) Set(value string) error {
+	var start, end uint16
+	var kinds string
+	if _, err := fmt.Sscanf(value, "0x%x-0x%x:%s", &start, &end, &kinds); err != nil {
+		return fmt.Errorf("want start-end:kinds, such as 0x0000-0x00FF:ro")
+	}
+	if start > end {
+		return fmt.Errorf("region 0x%04X-0x%04X ends before it starts", start, end)
+	}
+	region := processor.Region{Start: start, End: end}
+	for _, kind := range strings.Split(kinds, ",") {
+		switch kind {
+		case "ro":
+			region.Protection |= processor.ReadOnly
+		case "nx":
+			region.Protection |= processor.NoExecute
+		case "stack":
+			region.Protection |= processor.StackOnly
+		default:
+			return fmt.Errorf("unknown protection %q", kind)
+		}
+	}
+	*l = append(*l, region)
+	return nil
+}
+
+// runCommand executes a bytecode file.
+func runCommand(args []string) int {
+	opts := newRunOptions("gebvm")
+	opts.flags.Parse(args)
+	if opts.flags.NArg() != 1 {
+		fmt.Print(helpText)
+		return 1
+	}
+
+	e, err := loadExecutable(opts.flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	m, err := newMemory(opts)
+	if err == nil {
+		err = e.Load(m)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	table, err := loadSymbols(opts.flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return execute(opts, m, e, nil, table)
+}
+
+// resumeCommand continues execution from a snapshot file.
+func resumeCommand(args []string) int {
+	opts := newRunOptions("resume")
+	opts.flags.Parse(args)
+	if opts.flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, resumeHelpText)
+		return 1
+	}
+
+	f, err := os.Open(opts.flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading snapshot file: %s\n", err)
+		return 1
+	}
+	snapshot, err := processor.ReadSnapshot(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	// A snapshot taken at a step, fuel or time limit continues from the
+	// instruction the limit stopped before
+	var faults []error
+	for _, err := range snapshot.Errors {
+		var limit *processor.LimitError
+		if !errors.As(err, &limit) {
+			faults = append(faults, err)
+		}
+	}
+	if len(faults) > 0 {
+		fmt.Fprintf(os.Stderr, "snapshot was taken after execution errors:\n")
+		for _, err := range faults {
+			fmt.Fprintf(os.Stderr, "** %s\n", err)
+		}
+		return 1
+	}
+	snapshot.Errors = nil
+	// Coverage of a resumed program lists all of memory
+	e := &executable.Executable{Segments: []executable.Segment{{Address: 0x0000, Data: snapshot.Memory}}}
+	return execute(opts, memory.New(), e, snapshot, nil)
+}
+
+// execute runs a processor on m from the entry of e, or from the
+// snapshot if it is not nil, and handles the tracing, symbols, coverage
+// and snapshot options.  The symbols option replaces table.
+func execute(opts *runOptions, m processor.MemoryDevice, e *executable.Executable, snapshot *processor.Snapshot,
+	table *symbols.Table) int {
+	if *opts.symbolsFile != "" {
+		var err error
+		table, err = readSymbols(*opts.symbolsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+	}
+
+	if opts.banks.count > 0 && (*opts.snapshotFile != "" || snapshot != nil) {
+		fmt.Fprintf(os.Stderr, "snapshots do not include memory banks\n")
+		return 1
+	}
+
+	snapshotOn := map[string]bool{}
+	for _, event := range strings.Split(*opts.snapshotOn, ",") {
+		switch event {
+		case "halt", "signal", "error":
+			snapshotOn[event] = true
+		default:
+			fmt.Fprintf(os.Stderr, "unknown snapshot event %q\n", event)
+			return 1
+		}
+	}
+
+	switch *opts.coverageFmt {
+	case "lcov":
+		if *opts.coverageFile != "" && table == nil {
+			fmt.Fprintf(os.Stderr, "lcov coverage needs debug symbols, use --coverage-format html\n")
+			return 1
+		}
+	case "html":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown coverage format %q\n", *opts.coverageFmt)
+		return 1
+	}
+
+	var options []processor.Option
+	if table != nil {
+		options = append(options, processor.WithSymbols(table))
+	}
+	if len(opts.protect) > 0 {
+		options = append(options, processor.WithProtection(opts.protect...))
+	}
+	if *opts.stackGuard {
+		options = append(options, processor.WithStackGuard())
+	}
+	if *opts.maxSteps > 0 {
+		options = append(options, processor.WithStepLimit(*opts.maxSteps))
+	}
+	var tracer *trace.Writer
+	if *opts.traceFormat != "" {
+		var out io.Writer = os.Stderr
+		if *opts.traceFile != "" {
+			f, err := os.Create(*opts.traceFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error creating trace file: %s\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		}
+		switch *opts.traceFormat {
+		case "text":
+			tracer = trace.NewTextWriter(out)
+		case "json":
+			tracer = trace.NewJSONWriter(out)
+		default:
+			fmt.Fprintf(os.Stderr, "unknown trace format %q\n", *opts.traceFormat)
+			return 1
+		}
+		if table != nil {
+			tracer.SetLabels(table)
+		}
+		options = append(options, processor.WithTracer(tracer))
+	}
+
+	var profiler *profile.Profiler
+	if *opts.profile || *opts.pprofFile != "" {
+		profiler = profile.New(table)
+		options = append(options, processor.WithTracer(profiler))
+	}
+
+	var covered *coverage.Coverage
+	if *opts.coverageFile != "" {
+		covered = coverage.New()
+		options = append(options, processor.WithTracer(covered))
+	}
+
+	proc := processor.New(
+		m,
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		bufio.NewWriter(os.Stderr),
+		options...,
+	)
+	proc.SetInstructionPointer(e.Entry)
+	if snapshot != nil {
+		if err := proc.Restore(snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "error restoring snapshot: %s\n", err)
+			return 1
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	var received os.Signal
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if sig, ok := <-signals; ok {
+			received = sig
+			proc.Stop()
+		}
+	}()
+	ctx := context.Background()
+	if *opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *opts.timeout)
+		defer cancel()
+	}
+	status := proc.RunContext(ctx)
+	signal.Stop(signals)
+	close(signals)
+	<-done
+
+	if tracer != nil {
+		if err := tracer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing trace: %s\n", err)
+			return 1
+		}
+	}
+
+	if profiler != nil && !writeProfile(opts, profiler) {
+		return 1
+	}
+
+	if covered != nil && !writeCoverage(opts, covered.Listing(e.Segments, e.Entry, table)) {
+		return 1
+	}
+
+	event := "halt"
+	if received != nil {
+		event = "signal"
+		status = 128 + int(received.(syscall.Signal))
+	} else if status != 0 {
+		event = "error"
+		status = processor.ExitStatus(proc.Errors())
+	}
+	if *opts.snapshotFile != "" && snapshotOn[event] {
+		if err := writeSnapshot(*opts.snapshotFile, proc.Snapshot()); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing snapshot: %s\n", err)
+			return 1
+		}
+	}
+	return status
+}
+
+// writeProfile writes the profiling report and pprof file.
+func writeProfile(opts *runOptions, profiler *profile.Profiler) bool {
+	if *opts.profile {
+		if err := profiler.WriteReport(os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing profile report: %s\n", err)
+			return false
+		}
+	}
+	if *opts.pprofFile != "" {
+		f, err := os.Create(*opts.pprofFile)
+		if err == nil {
+			err = profiler.WritePprof(f, filepath.Base(opts.flags.Arg(0)))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing pprof file: %s\n", err)
+			return false
+		}
+	}
+	return true
+}
+
+// writeCoverage writes the coverage report and prints a summary to
+// stderr.
+func writeCoverage(opts *runOptions, lines []coverage.Line) bool {
+	f, err := os.Create(*opts.coverageFile)
+	if err == nil {
+		if *opts.coverageFmt == "html" {
+			err = coverage.WriteHTML(f, filepath.Base(opts.flags.Arg(0)), lines)
+		} else {
+			err = coverage.WriteLCOV(f, lines)
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing coverage report: %s\n", err)
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "coverage: %s\n", coverage.Summarize(lines))
+	return true
+}
+
+func writeSnapshot(filename string, snapshot *processor.Snapshot) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	_, err = snapshot.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+func TestResumeAfterStepLimit(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "program.bin")
+	err := ioutil.WriteFile(program, []uint8{
+		processor.MoveLitReg, 0x01, 0x00, // 0x0000
+		processor.MoveLitReg, 0x02, 0x01, // 0x0003
+		processor.MoveLitReg, 0x03, 0x02, // 0x0006
+		processor.Halt, // 0x0009
+	}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited := filepath.Join(dir, "limited.snap")
+	halted := filepath.Join(dir, "halted.snap")
+
+	if status := runCommand([]string{"--max-steps", "2", "--snapshot", limited, program}); status != 20 {
+		t.Fatalf("got status %d at the step limit, want 20", status)
+	}
+	if status := resumeCommand([]string{"--snapshot", halted, limited}); status != 0 {
+		t.Fatalf("got status %d resuming, want 0", status)
+	}
+
+	f, err := os.Open(halted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	snapshot, err := processor.ReadSnapshot(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Errors) != 0 {
+		t.Errorf("got errors %v after resuming, want none", snapshot.Errors)
+	}
+	if snapshot.Registers != [processor.RegisterCount]uint8{0x01, 0x02, 0x03} {
+		t.Errorf("got registers % X, want 01 02 03 and zeros", snapshot.Registers)
+	}
+	if snapshot.InstructionPointer != 0x000A {
+		t.Errorf("got IP 0x%04X, want 0x000A after the HLT", snapshot.InstructionPointer)
+	}
+}
+
+func TestResumeRefusesFaults(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "program.bin")
+	if err := ioutil.WriteFile(program, []uint8{processor.StackPop, 0x00}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	faulted := filepath.Join(dir, "faulted.snap")
+
+	if status := runCommand([]string{"--snapshot", faulted, program}); status == 0 {
+		t.Fatalf("got status 0 popping an empty stack, want an error")
+	}
+	if status := resumeCommand([]string{faulted}); status != 1 {
+		t.Errorf("got status %d resuming after a fault, want 1", status)
+	}
+}
@@ -0,0 +1,132 @@
+// Package symbols maps bytecode addresses to source lines and labels.
+//
+// Symbol tables are written by the assembler to a JSON sidecar file with
+// the same name as the bytecode file and a .sym extension.
+package symbols
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Line is the source line that output Size bytes at Address.
+type Line struct {
+	Address uint16 `json:"address"`
+	Size    uint16 `json:"size"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Data    bool   `json:"data,omitempty"` // Output by a directive instead of an instruction
+}
+
+// Table holds the symbols for a program.  Tables are made with New, and
+// must not be changed afterwards.
+type Table struct {
+	Lines  []Line            `json:"lines"`  // Sorted by address
+	Labels map[string]uint16 `json:"labels"` // Address of each label
+
+	labelAddresses []labelAddress // First label at each address, sorted by address
+}
+
+// labelAddress is the label LabelAt returns for an address.
+type labelAddress struct {
+	address uint16
+	name    string
+}
+
+// New returns a Table for lines, in any order, and labels.
+func New(lines []Line, labels map[string]uint16) *Table {
+	t := &Table{Lines: append([]Line{}, lines...), Labels: labels}
+	if t.Labels == nil {
+		t.Labels = map[string]uint16{}
+	}
+	sort.Slice(t.Lines, func(i, j int) bool { return t.Lines[i].Address < t.Lines[j].Address })
+
+	for name, address := range t.Labels {
+		t.labelAddresses = append(t.labelAddresses, labelAddress{address: address, name: name})
+	}
+	sort.Slice(t.labelAddresses, func(i, j int) bool {
+		a, b := t.labelAddresses[i], t.labelAddresses[j]
+		return a.address < b.address || (a.address == b.address && a.name < b.name)
+	})
+	// Keep the first label in alphabetical order at each address
+	unique := t.labelAddresses[:0]
+	for _, l := range t.labelAddresses {
+		if len(unique) == 0 || unique[len(unique)-1].address != l.address {
+			unique = append(unique, l)
+		}
+	}
+	t.labelAddresses = unique
+	return t
+}
+
+// SidecarName returns the symbol file name for a bytecode file.
+func SidecarName(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".sym"
+}
+
+// Read reads a Table from a symbol file.
+func Read(r io.Reader) (*Table, error) {
+	var t Table
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("error reading symbols: %s", err)
+	}
+	return New(t.Lines, t.Labels), nil
+}
+
+// Write writes the Table as a symbol file.
+func (t *Table) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// Location returns the source line that output the byte at address.
+func (t *Table) Location(address uint16) (Line, bool) {
+	i := sort.Search(len(t.Lines), func(i int) bool { return t.Lines[i].Address > address }) - 1
+	if i < 0 || int(address) >= int(t.Lines[i].Address)+int(t.Lines[i].Size) {
+		return Line{}, false
+	}
+	return t.Lines[i], true
+}
+
+// LabelAt returns the label for address.  When several labels share an
+// address the first in alphabetical order is returned.
+func (t *Table) LabelAt(address uint16) (string, bool) {
+	label, offset, found := t.Nearest(address)
+	if !found || offset != 0 {
+		return "", false
+	}
+	return label, true
+}
+
+// Nearest returns the closest label at or before address and the offset
+// of address from it.
+func (t *Table) Nearest(address uint16) (string, uint16, bool) {
+	i := sort.Search(len(t.labelAddresses), func(i int) bool { return t.labelAddresses[i].address > address }) - 1
+	if i < 0 {
+		return "", 0, false
+	}
+	l := t.labelAddresses[i]
+	return l.name, address - l.address, true
+}
+
+// Describe returns the source location and nearest label for address,
+// such as "hello.asm:3 (loop+0x2)", or "" if neither is known.
+func (t *Table) Describe(address uint16) string {
+	var parts []string
+	if line, found := t.Location(address); found {
+		parts = append(parts, fmt.Sprintf("%s:%d", line.File, line.Line))
+	}
+	if label, offset, found := t.Nearest(address); found {
+		if offset == 0 {
+			parts = append(parts, fmt.Sprintf("(%s)", label))
+		} else {
+			parts = append(parts, fmt.Sprintf("(%s+0x%X)", label, offset))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,106 @@
+package symbols_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/symbols"
+)
+
+func testTable() *symbols.Table {
+	return symbols.New(
+		[]symbols.Line{
+			{Address: 0x0004, Size: 1, File: "test.asm", Line: 3},
+			{Address: 0x0000, Size: 4, File: "test.asm", Line: 2},
+		},
+		map[string]uint16{"start": 0x0000, "main": 0x0000, "message": 0x0005},
+	)
+}
+
+func TestLocation(t *testing.T) {
+	table := testTable()
+	tests := []struct {
+		address uint16
+		line    int
+		found   bool
+	}{
+		{address: 0x0000, line: 2, found: true},
+		{address: 0x0003, line: 2, found: true},
+		{address: 0x0004, line: 3, found: true},
+		{address: 0x0005, found: false},
+	}
+	for _, test := range tests {
+		line, found := table.Location(test.address)
+		if found != test.found || line.Line != test.line {
+			t.Errorf("got line %d, %t for 0x%04X, want %d, %t", line.Line, found, test.address, test.line, test.found)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	table := testTable()
+	if label, found := table.LabelAt(0x0000); !found || label != "main" {
+		t.Errorf("got %q, %t, want main", label, found)
+	}
+	if _, found := table.LabelAt(0x0001); found {
+		t.Error("got true, want no label at 0x0001")
+	}
+	if label, offset, found := table.Nearest(0x0003); !found || label != "main" || offset != 3 {
+		t.Errorf("got %q+%d, %t, want main+3", label, offset, found)
+	}
+
+	table = symbols.New(nil, map[string]uint16{"loop": 0x0010, "again": 0x0010, "end": 0x0020})
+	if label, found := table.LabelAt(0x0010); !found || label != "again" {
+		t.Errorf("got %q, %t, want again, the first label in alphabetical order", label, found)
+	}
+	if label, offset, found := table.Nearest(0x001F); !found || label != "again" || offset != 0xF {
+		t.Errorf("got %q+%d, %t, want again+15", label, offset, found)
+	}
+	if label, offset, found := table.Nearest(0xFFFF); !found || label != "end" || offset != 0xFFDF {
+		t.Errorf("got %q+%d, %t, want end+0xFFDF", label, offset, found)
+	}
+	if _, _, found := table.Nearest(0x000F); found {
+		t.Error("got true, want no label before 0x0010")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	table := testTable()
+	tests := []struct {
+		address  uint16
+		expected string
+	}{
+		{address: 0x0002, expected: "test.asm:2 (main+0x2)"},
+		{address: 0x0004, expected: "test.asm:3 (main+0x4)"},
+		{address: 0x0005, expected: "(message)"},
+	}
+	for _, test := range tests {
+		if actual := table.Describe(test.address); actual != test.expected {
+			t.Errorf("got %q for 0x%04X, want %q", actual, test.address, test.expected)
+		}
+	}
+	if actual := symbols.New(nil, nil).Describe(0x0000); actual != "" {
+		t.Errorf("got %q, want empty description", actual)
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	var b bytes.Buffer
+	if err := testTable().Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	table, err := symbols.Read(&b)
+	if err != nil {
+		t.Fatalf("got error %q, want nil", err)
+	}
+	if !reflect.DeepEqual(table, testTable()) {
+		t.Errorf("got %+v, want %+v", table, testTable())
+	}
+}
+
+func TestSidecarName(t *testing.T) {
+	if actual := symbols.SidecarName("dir/hello.geb"); actual != "dir/hello.sym" {
+		t.Errorf("got %q, want dir/hello.sym", actual)
+	}
+}
@@ -0,0 +1,138 @@
+// Package trace writes processor trace records as readable text or as
+// JSON Lines.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/scottmcleodjr/gebvm/disassembler"
+	"github.com/scottmcleodjr/gebvm/processor"
+)
+
+// Writer is a processor.Tracer that writes each record to an io.Writer.
+// Output is buffered, so Flush must be called when execution ends.
+type Writer struct {
+	writer *bufio.Writer
+	format func(*Writer, *processor.TraceRecord) ([]byte, error)
+	labels disassembler.Labeler // names for jump targets, or nil
+	err    error                // first error encountered while writing
+}
+
+// NewTextWriter returns a Writer for readable text with one line per record.
+func NewTextWriter(w io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(w), format: formatText}
+}
+
+// NewJSONWriter returns a Writer for JSON Lines with one object per record.
+func NewJSONWriter(w io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(w), format: formatJSON}
+}
+
+// Trace writes a record.  Errors are returned by Flush.
+func (w *Writer) Trace(record *processor.TraceRecord) {
+	if w.err != nil {
+		return
+	}
+	line, err := w.format(w, record)
+	if err == nil {
+		_, err = w.writer.Write(line)
+	}
+	w.err = err
+}
+
+// SetLabels makes the Writer show labels in place of the address
+// operands that have one.
+func (w *Writer) SetLabels(labels disassembler.Labeler) {
+	w.labels = labels
+}
+
+// Flush writes any buffered records and returns the first error
+// encountered while writing.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.writer.Flush()
+}
+
+// instructionText returns the assembly text of the traced instruction.
+func (w *Writer) instructionText(record *processor.TraceRecord) string {
+	bytes := append([]uint8{record.Opcode}, record.Operands...)
+	line := disassembler.Linear(bytes, record.InstructionPointer)[0]
+	if line.Instruction == nil {
+		return fmt.Sprintf(".byte 0x%02X", record.Opcode)
+	}
+	return line.TextWithLabels(w.labels)
+}
+
+func formatRegisters(registers [processor.RegisterCount]uint8) string {
+	return fmt.Sprintf("% X", registers[:])
+}
+
+// formatText writes a record as
+//
+//	0006  MRM R1, R2            00 2A 00 00 00 00 00 00 ---- -> 00 2A 00 00 00 00 00 00 ----  [0001]=2A
+func formatText(w *Writer, record *processor.TraceRecord) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%04X  %-20s  %s %s -> %s %s", record.InstructionPointer, w.instructionText(record),
+		formatRegisters(record.RegistersBefore), processor.FlagString(record.FlagsBefore),
+		formatRegisters(record.RegistersAfter), processor.FlagString(record.FlagsAfter))
+	for _, write := range record.MemoryWrites {
+		fmt.Fprintf(&b, "  [%04X]=%02X", write.Address, write.Value)
+	}
+	b.WriteByte('\n')
+	return []byte(b.String()), nil
+}
+
+type jsonMemoryWrite struct {
+	Address uint16 `json:"address"`
+	Value   uint8  `json:"value"`
+}
+
+type jsonRecord struct {
+	InstructionPointer     uint16            `json:"ip"`
+	Opcode                 uint8             `json:"opcode"`
+	Mnemonic               string            `json:"mnemonic"`
+	Operands               []uint16          `json:"operands"`
+	Text                   string            `json:"text"`
+	RegistersBefore        []uint16          `json:"registers_before"`
+	RegistersAfter         []uint16          `json:"registers_after"`
+	FlagsBefore            uint8             `json:"flags_before"`
+	FlagsAfter             uint8             `json:"flags_after"`
+	NextInstructionPointer uint16            `json:"next_ip"`
+	MemoryWrites           []jsonMemoryWrite `json:"memory_writes"`
+}
+
+// numbers converts bytes so they are encoded as a JSON array of numbers.
+func numbers(values []uint8) []uint16 {
+	out := make([]uint16, len(values))
+	for i, value := range values {
+		out[i] = uint16(value)
+	}
+	return out
+}
+
+func formatJSON(w *Writer, record *processor.TraceRecord) ([]byte, error) {
+	r := jsonRecord{
+		InstructionPointer:     record.InstructionPointer,
+		Opcode:                 record.Opcode,
+		Mnemonic:               record.Mnemonic,
+		Operands:               numbers(record.Operands),
+		Text:                   w.instructionText(record),
+		RegistersBefore:        numbers(record.RegistersBefore[:]),
+		RegistersAfter:         numbers(record.RegistersAfter[:]),
+		FlagsBefore:            record.FlagsBefore,
+		FlagsAfter:             record.FlagsAfter,
+		NextInstructionPointer: record.NextInstructionPointer,
+		MemoryWrites:           make([]jsonMemoryWrite, len(record.MemoryWrites)),
+	}
+	for i, write := range record.MemoryWrites {
+		r.MemoryWrites[i] = jsonMemoryWrite{Address: write.Address, Value: write.Value}
+	}
+	line, err := json.Marshal(r)
+	return append(line, '\n'), err
+}
@@ -0,0 +1,98 @@
+package trace_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/scottmcleodjr/gebvm/processor"
+	"github.com/scottmcleodjr/gebvm/trace"
+)
+
+var testRecord = processor.TraceRecord{
+	InstructionPointer:     0x0006,
+	Opcode:                 processor.MoveRegMem,
+	Mnemonic:               "MRM",
+	Operands:               []uint8{0x01, 0x02},
+	RegistersBefore:        [processor.RegisterCount]uint8{0x00, 0x2A, 0x00, 0x01},
+	RegistersAfter:         [processor.RegisterCount]uint8{0x00, 0x2A, 0x00, 0x01},
+	FlagsAfter:             processor.FlagNegative,
+	NextInstructionPointer: 0x0009,
+	MemoryWrites:           []processor.MemoryWrite{{Address: 0x0001, Value: 0x2A}},
+}
+
+func TestTextWriter(t *testing.T) {
+	var out strings.Builder
+	w := trace.NewTextWriter(&out)
+	w.Trace(&testRecord)
+	w.Trace(&processor.TraceRecord{InstructionPointer: 0x0009, Opcode: 0x0F})
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "0006  MRM R1, R2            00 2A 00 01 00 00 00 00 ---- -> 00 2A 00 01 00 00 00 00 ---N  [0001]=2A\n" +
+		"0009  .byte 0x0F            00 00 00 00 00 00 00 00 ---- -> 00 00 00 00 00 00 00 00 ----\n"
+	if out.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+type testLabels map[uint16]string
+
+func (l testLabels) LabelAt(address uint16) (string, bool) {
+	label, found := l[address]
+	return label, found
+}
+
+func TestTextWriterLabels(t *testing.T) {
+	var out strings.Builder
+	w := trace.NewTextWriter(&out)
+	w.SetLabels(testLabels{0x0010: "loop"})
+	w.Trace(&processor.TraceRecord{
+		Opcode:   processor.Jump,
+		Mnemonic: "JMP",
+		Operands: []uint8{0x00, 0x10},
+	})
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "0000  JMP loop  ") {
+		t.Errorf("got %q, want JMP loop", out.String())
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var out strings.Builder
+	w := trace.NewJSONWriter(&out)
+	w.Trace(&testRecord)
+	w.Trace(&testRecord)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var record struct {
+		IP              uint16 `json:"ip"`
+		Text            string `json:"text"`
+		Operands        []int  `json:"operands"`
+		RegistersBefore []int  `json:"registers_before"`
+		NextIP          uint16 `json:"next_ip"`
+		FlagsAfter      uint8  `json:"flags_after"`
+		MemoryWrites    []struct {
+			Address int `json:"address"`
+			Value   int `json:"value"`
+		} `json:"memory_writes"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.IP != 0x0006 || record.Text != "MRM R1, R2" || record.NextIP != 0x0009 || record.FlagsAfter != processor.FlagNegative ||
+		len(record.Operands) != 2 || len(record.RegistersBefore) != 8 || record.RegistersBefore[1] != 0x2A {
+		t.Errorf("got %+v", record)
+	}
+	if len(record.MemoryWrites) != 1 || record.MemoryWrites[0].Address != 1 || record.MemoryWrites[0].Value != 0x2A {
+		t.Errorf("got %+v for memory writes", record.MemoryWrites)
+	}
+}